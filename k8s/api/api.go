@@ -1,8 +1,13 @@
 package api
 
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
 // Application defines the interface that all versions of the API must adhere to in the star API versioning scheme
 type Application interface {
-	// Replicas returns the number of replicas a deployment must have
+	// Replicas returns the number of replicas a deployment must have, nil leaves the replica count to the deployment or
+	// to an autoscaler
 	Replicas() *int32
 
 	// ServiceAccount is an optional field which will pass in a predefined service account name to use
@@ -14,6 +19,9 @@ type Application interface {
 	// Image defines the FQDN for the pull location for the Application's container image
 	Image() string
 
+	// PinImageDigest defines if the Application's container image is deployed pinned to the digest it resolves to
+	PinImageDigest() bool
+
 	// Port defines the port to expose from the Application's container
 	Port() *int32
 
@@ -23,6 +31,24 @@ type Application interface {
 	// Version defines the application version running
 	Version() *string
 
+	// RevisionHistoryLimit defines how many previous revisions of the Application spec are kept for rollback
+	RevisionHistoryLimit() *int32
+
+	// Suspend defines if the reconciliation of the Application's cluster state is paused
+	Suspend() bool
+
+	// Resources defines the compute resources of the Application's container, none are set when nil
+	Resources() *corev1.ResourceRequirements
+
+	// Labels defines the additional labels set on every generated object, on top of the labels the generators set
+	Labels() map[string]string
+
+	// IngressClassName defines the class of the ingress controller that serves the Application
+	IngressClassName() *string
+
+	// IngressAnnotations defines the annotations of the generated ingress, which configure the ingress controller
+	IngressAnnotations() map[string]string
+
 	// Instancer derives the UUID instance truncation from the CR's generated UUID in etcd
 	Instancer() *string
 }
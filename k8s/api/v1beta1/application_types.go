@@ -17,7 +17,13 @@ limitations under the License.
 package v1beta1
 
 import (
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
 
 	acmeioutils "github.com/nathanbrophy/portfolio-demo/k8s/utils"
 )
@@ -26,6 +32,64 @@ const (
 	NAME            string = "acme-application"
 	SERVICE_ACCOUNT string = NAME + "-sa"
 	VERSION         string = "v1.0.0"
+	INGRESS_CLASS   string = "alb"
+)
+
+// Condition types reported in the status of an Application
+const (
+	// ConditionImagePolicyViolation is true when the image of the Application does not satisfy the operator image policy
+	ConditionImagePolicyViolation string = "ImagePolicyViolation"
+
+	// ConditionSuspended is true when the reconciliation of the Application's cluster state is suspended
+	ConditionSuspended string = "Suspended"
+
+	// ConditionOwnershipConflict is true when downstream objects exist that the Application does not control, and may not adopt
+	ConditionOwnershipConflict string = "OwnershipConflict"
+
+	// ConditionDriftDetected is true when downstream objects have drifted, and the drift policy leaves the drift uncorrected
+	ConditionDriftDetected string = "DriftDetected"
+)
+
+// DeletionPolicy defines what happens to a downstream object when its Application is deleted
+// +kubebuilder:validation:Enum=Delete;Orphan;Retain
+type DeletionPolicy string
+
+const (
+	// DeletionPolicyDelete deletes the object along with the Application
+	DeletionPolicyDelete DeletionPolicy = "Delete"
+
+	// DeletionPolicyOrphan detaches the object from the Application and leaves it on the cluster
+	DeletionPolicyOrphan DeletionPolicy = "Orphan"
+
+	// DeletionPolicyRetain detaches the object from the Application, and marks it so that an Application of the same name adopts it again
+	DeletionPolicyRetain DeletionPolicy = "Retain"
+)
+
+// DriftPolicy defines what the controller does with a downstream object that has drifted from the state defined by its Application
+// +kubebuilder:validation:Enum=Correct;Report;Ignore
+type DriftPolicy string
+
+const (
+	// DriftPolicyCorrect reports the drift and brings the object back to the state defined by the Application
+	DriftPolicyCorrect DriftPolicy = "Correct"
+
+	// DriftPolicyReport reports the drift and leaves the object as it is
+	DriftPolicyReport DriftPolicy = "Report"
+
+	// DriftPolicyIgnore leaves the object as it is without looking for drift, the object is still created when it is missing
+	DriftPolicyIgnore DriftPolicy = "Ignore"
+)
+
+// OverridePatchType defines how the patch of an override is applied to a downstream object
+// +kubebuilder:validation:Enum=StrategicMerge;JSON6902
+type OverridePatchType string
+
+const (
+	// OverridePatchTypeStrategicMerge merges the patch into the object as kubectl patch does, lists such as containers are merged by name
+	OverridePatchTypeStrategicMerge OverridePatchType = "StrategicMerge"
+
+	// OverridePatchTypeJSON6902 applies the patch as a list of RFC 6902 JSON patch operations
+	OverridePatchTypeJSON6902 OverridePatchType = "JSON6902"
 )
 
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
@@ -55,13 +119,18 @@ type ApplicationApplication struct {
 	// Image defines the FQDN / Pull Location for the container image to run and is required
 	Image *string `json:"image"`
 
-	// Replicas is the number of replicas to run for the downstream deployment
+	// Replicas is the number of replicas to run for the downstream deployment, when unset the replica count is left to
+	// the deployment, or to an autoscaler such as a HorizontalPodAutoscaler
 	//+optional
 	Replicas *int32 `json:"replicas,omitempty"`
 
 	// Port is the port to expose from the container
 	//++optional
 	Port *int32 `json:"port,omitempty"`
+
+	// PinImageDigest will resolve the image to its digest through the registry API, and deploy the image pinned to that digest
+	//+optional
+	PinImageDigest *bool `json:"pinImageDigest,omitempty"`
 }
 
 // ApplicationSpec defines the desired state of Application
@@ -74,6 +143,153 @@ type ApplicationSpec struct {
 
 	// BoilerPlate defines bootstrap / helpful information and metadata to be used and is not tied directly to the application
 	BoilerPlate *ApplicationBoilerPlate `json:"boilerPlate,omitempty"`
+
+	// RevisionHistoryLimit is the number of previous revisions of the spec to retain for rollback, and defaults to 10
+	//+optional
+	//+kubebuilder:validation:Minimum=0
+	RevisionHistoryLimit *int32 `json:"revisionHistoryLimit,omitempty"`
+
+	// RollbackTo will re-apply the spec recorded in a previous revision, and is cleared once the rollback is complete
+	//+optional
+	RollbackTo *ApplicationRollback `json:"rollbackTo,omitempty"`
+
+	// Suspend stops the controller from creating or updating the downstream objects, drift is still detected and reported
+	//+optional
+	Suspend *bool `json:"suspend,omitempty"`
+
+	// Hibernation scales the Application down to zero replicas during a recurring window, such as overnight and on weekends
+	//+optional
+	Hibernation *ApplicationHibernation `json:"hibernation,omitempty"`
+
+	// DeletionPolicy defines what happens to the downstream objects when the Application is deleted
+	//+optional
+	DeletionPolicy *ApplicationDeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// DriftPolicy defines what the controller does with downstream objects that have drifted from the state defined by the Application
+	//+optional
+	DriftPolicy *ApplicationDriftPolicy `json:"driftPolicy,omitempty"`
+
+	// DriftIgnore lists fields of the downstream objects that other actors legitimately change, which are left out when looking for drift
+	//+optional
+	DriftIgnore []ApplicationDriftIgnoreRule `json:"driftIgnore,omitempty"`
+
+	// Overrides patch the generated downstream objects, to set fields the Application has no setting for, they are applied in order
+	//+optional
+	Overrides []ApplicationOverride `json:"overrides,omitempty"`
+
+	// ExtraResources are additional objects, such as a ConfigMap or a CronJob, that are created in the namespace of the Application and deleted along with it, of the kinds the operator allows
+	//+optional
+	ExtraResources []runtime.RawExtension `json:"extraResources,omitempty"`
+}
+
+// ApplicationOverride defines a patch that is applied to a generated downstream object before it is created or updated
+type ApplicationOverride struct {
+	// Kind is the kind of the downstream object to patch, such as Deployment
+	//+kubebuilder:validation:MinLength=1
+	Kind string `json:"kind"`
+
+	// Type is the type of the patch, and defaults to StrategicMerge
+	//+optional
+	Type OverridePatchType `json:"type,omitempty"`
+
+	// Patch is the patch as YAML or JSON, a strategic merge patch is a partial object and a JSON6902 patch is a list of operations
+	//+kubebuilder:validation:MinLength=1
+	Patch string `json:"patch"`
+}
+
+// ApplicationDriftIgnoreRule defines fields of the downstream objects that are left out when looking for drift
+type ApplicationDriftIgnoreRule struct {
+	// Kind limits the rule to a single kind of downstream object, such as Deployment, the rule applies to every kind when empty
+	//+optional
+	Kind string `json:"kind,omitempty"`
+
+	// Paths are the fields to ignore, along with every field below them, as JSON pointers such as
+	// /metadata/annotations/alb.ingress.kubernetes.io~1scheme or as field paths such as spec.template.spec.containers[*].image
+	//+kubebuilder:validation:MinItems=1
+	Paths []string `json:"paths"`
+}
+
+// ApplicationDriftPolicy defines what the controller does with each kind of downstream object that has drifted
+type ApplicationDriftPolicy struct {
+	// Default is the drift policy of every kind without a policy of its own, and defaults to Correct
+	//+optional
+	Default DriftPolicy `json:"default,omitempty"`
+
+	// Kinds are the drift policies of single kinds of downstream object, such as Deployment or one of the kinds of the extra resources
+	//+optional
+	//+listType=map
+	//+listMapKey=kind
+	Kinds []ApplicationKindDriftPolicy `json:"kinds,omitempty"`
+}
+
+// ApplicationKindDriftPolicy defines the drift policy of a single kind of downstream object
+type ApplicationKindDriftPolicy struct {
+	// Kind is the kind of the downstream object, such as Deployment or Service
+	//+kubebuilder:validation:MinLength=1
+	Kind string `json:"kind"`
+
+	// Policy is the drift policy of the kind
+	Policy DriftPolicy `json:"policy"`
+}
+
+// ApplicationDeletionPolicy defines what happens to each kind of downstream object when the Application is deleted
+type ApplicationDeletionPolicy struct {
+	// Default is the deletion policy of every kind without a policy of its own, and defaults to Delete
+	//+optional
+	Default DeletionPolicy `json:"default,omitempty"`
+
+	// Kinds are the deletion policies of single kinds of downstream object, such as Deployment or one of the kinds of the extra resources
+	//+optional
+	//+listType=map
+	//+listMapKey=kind
+	Kinds []ApplicationKindDeletionPolicy `json:"kinds,omitempty"`
+
+	// PreDelete defines the steps taken before the downstream objects are removed
+	//+optional
+	PreDelete *ApplicationPreDelete `json:"preDelete,omitempty"`
+}
+
+// ApplicationKindDeletionPolicy defines the deletion policy of a single kind of downstream object
+type ApplicationKindDeletionPolicy struct {
+	// Kind is the kind of the downstream object, such as Deployment or Service
+	//+kubebuilder:validation:MinLength=1
+	Kind string `json:"kind"`
+
+	// Policy is the deletion policy of the kind
+	Policy DeletionPolicy `json:"policy"`
+}
+
+// ApplicationPreDelete defines the steps taken before the downstream objects of a deleted Application are removed
+type ApplicationPreDelete struct {
+	// Drain scales the Deployment to zero replicas, and waits for the endpoints of the Service to drain
+	//+optional
+	Drain bool `json:"drain,omitempty"`
+
+	// TimeoutSeconds bounds how long the drain is waited for, counted from the deletion of the Application, and defaults to 300
+	//+optional
+	//+kubebuilder:validation:Minimum=0
+	TimeoutSeconds *int32 `json:"timeoutSeconds,omitempty"`
+}
+
+// ApplicationHibernation defines the recurring window during which the Application is scaled to zero replicas
+type ApplicationHibernation struct {
+	// Sleep is a standard cron schedule of when the Application is scaled to zero replicas, such as "0 20 * * 1-5"
+	Sleep string `json:"sleep"`
+
+	// Wake is a standard cron schedule of when the Application's replicas are restored, such as "0 7 * * 1-5"
+	Wake string `json:"wake"`
+
+	// TimeZone is the IANA time zone the schedules are evaluated in, such as "Europe/Berlin", and defaults to UTC
+	//+optional
+	TimeZone string `json:"timeZone,omitempty"`
+}
+
+// ApplicationRollback defines the revision of the Application spec to roll back to
+type ApplicationRollback struct {
+	// Revision is the revision number to roll back to, where 0 will roll back to the revision prior to the current one
+	//+optional
+	//+kubebuilder:validation:Minimum=0
+	Revision int64 `json:"revision,omitempty"`
 }
 
 // ApplicationStatus defines the observed state of Application
@@ -86,6 +302,103 @@ type ApplicationStatus struct {
 
 	// Reason defines why progressing is true or false
 	Reason string `json:"reason"`
+
+	// ObservedGeneration is the generation of the Application spec the status was last reconciled from
+	//+optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// CurrentRevision is the name of the ControllerRevision holding the spec currently being reconciled
+	//+optional
+	CurrentRevision string `json:"currentRevision,omitempty"`
+
+	// Revision is the revision number of the spec currently being reconciled
+	//+optional
+	Revision int64 `json:"revision,omitempty"`
+
+	// ImageDigest is the digest the image was resolved to when image digest pinning is enabled, the image is resolved
+	// again for every new generation of the Application spec
+	//+optional
+	ImageDigest string `json:"imageDigest,omitempty"`
+
+	// ResolvedImage is the pinned image reference deployed when image digest pinning is enabled
+	//+optional
+	ResolvedImage string `json:"resolvedImage,omitempty"`
+
+	// Hibernating is true while the Application is scaled to zero replicas by its hibernation window
+	//+optional
+	Hibernating bool `json:"hibernating,omitempty"`
+
+	// NextHibernationTransition is the time the Application is next put to sleep, or woken up, by its hibernation window
+	//+optional
+	NextHibernationTransition *metav1.Time `json:"nextHibernationTransition,omitempty"`
+
+	// Children reports the outcome of the last reconciliation of every downstream object
+	//+optional
+	//+listType=map
+	//+listMapKey=kind
+	//+listMapKey=name
+	Children []ApplicationChildStatus `json:"children,omitempty"`
+
+	// RecentDrift lists the most recent drift of the downstream objects from the state defined by the Application, newest first
+	//+optional
+	RecentDrift []ApplicationDriftRecord `json:"recentDrift,omitempty"`
+
+	// Conditions defines the latest available observations of the state of the Application
+	//+optional
+	//+listType=map
+	//+listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// ApplicationChildStatus defines the observed state of a single downstream object of the Application
+type ApplicationChildStatus struct {
+	// APIVersion is the group and version of the downstream object
+	//+optional
+	APIVersion string `json:"apiVersion,omitempty"`
+
+	// Kind is the kind of the downstream object
+	Kind string `json:"kind"`
+
+	// Name is the name of the downstream object
+	Name string `json:"name"`
+
+	// Synced is true when the downstream object is in the state defined by the Application
+	Synced bool `json:"synced"`
+
+	// Reason is the outcome of the last reconciliation of the object, one of Created, Updated, Adopted, InSync, Drifted, DriftIgnored, OwnershipConflict or Failed
+	Reason string `json:"reason"`
+
+	// Message is a human readable description of the outcome, such as the error the reconciliation failed with
+	//+optional
+	Message string `json:"message,omitempty"`
+}
+
+// ApplicationDriftRecord defines a drift of a downstream object from the state defined by the Application
+type ApplicationDriftRecord struct {
+	// Time is when the drift was detected
+	Time metav1.Time `json:"time"`
+
+	// Kind is the kind of the drifted object
+	Kind string `json:"kind"`
+
+	// Name is the name of the drifted object
+	Name string `json:"name"`
+
+	// Differences lists the fields of the object that drifted
+	Differences []ApplicationDriftDifference `json:"differences"`
+}
+
+// ApplicationDriftDifference defines a single field of a downstream object that drifted
+type ApplicationDriftDifference struct {
+	// Path is the JSON pointer to the field, such as /spec/replicas
+	Path string `json:"path"`
+
+	// Expected is the JSON encoded value of the field in the state defined by the Application
+	Expected string `json:"expected"`
+
+	// Actual is the JSON encoded value of the field on the cluster, empty when the field is not set
+	//+optional
+	Actual string `json:"actual,omitempty"`
 }
 
 //+kubebuilder:object:root=true
@@ -118,8 +431,8 @@ type ApplicationList struct {
  */
 
 func (a *Application) Replicas() *int32 {
-	if a == nil || a.Spec.Application == nil || a.Spec.Application.Replicas == nil {
-		return acmeioutils.Int32PointerGenerator(1)
+	if a == nil || a.Spec.Application == nil {
+		return nil
 	}
 
 	return a.Spec.Application.Replicas
@@ -141,6 +454,14 @@ func (a *Application) Port() *int32 {
 	return a.Spec.Application.Port
 }
 
+func (a *Application) PinImageDigest() bool {
+	if a == nil || a.Spec.Application == nil || a.Spec.Application.PinImageDigest == nil {
+		return false
+	}
+
+	return *a.Spec.Application.PinImageDigest
+}
+
 func (a *Application) ServiceAccount() *string {
 	if a == nil || a.Spec.BoilerPlate == nil || a.Spec.BoilerPlate.ServiceAccount == nil {
 		return acmeioutils.StringPointerGenerator(SERVICE_ACCOUNT)
@@ -173,6 +494,101 @@ func (a *Application) Version() *string {
 	return a.Spec.BoilerPlate.Version
 }
 
+func (a *Application) RevisionHistoryLimit() *int32 {
+	if a == nil || a.Spec.RevisionHistoryLimit == nil {
+		return acmeioutils.Int32PointerGenerator(10)
+	}
+
+	return a.Spec.RevisionHistoryLimit
+}
+
+func (a *Application) Suspend() bool {
+	if a == nil || a.Spec.Suspend == nil {
+		return false
+	}
+
+	return *a.Spec.Suspend
+}
+
+// DeletionPolicyFor returns the deletion policy of the given downstream kind, falling back on the default policy
+func (a *Application) DeletionPolicyFor(kind string) DeletionPolicy {
+	if a == nil || a.Spec.DeletionPolicy == nil {
+		return DeletionPolicyDelete
+	}
+
+	for _, policy := range a.Spec.DeletionPolicy.Kinds {
+		if policy.Kind == kind {
+			return policy.Policy
+		}
+	}
+	if a.Spec.DeletionPolicy.Default != "" {
+		return a.Spec.DeletionPolicy.Default
+	}
+
+	return DeletionPolicyDelete
+}
+
+// DriftPolicyFor returns the drift policy of the given downstream kind, falling back on the default policy
+func (a *Application) DriftPolicyFor(kind string) DriftPolicy {
+	if a == nil || a.Spec.DriftPolicy == nil {
+		return DriftPolicyCorrect
+	}
+
+	for _, policy := range a.Spec.DriftPolicy.Kinds {
+		if policy.Kind == kind {
+			return policy.Policy
+		}
+	}
+	if a.Spec.DriftPolicy.Default != "" {
+		return a.Spec.DriftPolicy.Default
+	}
+
+	return DriftPolicyCorrect
+}
+
+// ExtraResource decodes the extra resource at the given index, an extra resource must be an object with an apiVersion, a kind and a name
+func (a *Application) ExtraResource(i int) (*unstructured.Unstructured, error) {
+	raw := a.Spec.ExtraResources[i]
+
+	obj := &unstructured.Unstructured{}
+	if raw.Raw == nil && raw.Object != nil {
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(raw.Object)
+		if err != nil {
+			return nil, err
+		}
+		obj.SetUnstructuredContent(content)
+	} else if err := obj.UnmarshalJSON(raw.Raw); err != nil {
+		return nil, err
+	}
+
+	if obj.GetAPIVersion() == "" || obj.GetKind() == "" {
+		return nil, fmt.Errorf("the extra resource has no apiVersion or kind")
+	}
+	if obj.GetName() == "" {
+		return nil, fmt.Errorf("%s has no name, generated names are not supported", obj.GetKind())
+	}
+
+	return obj, nil
+}
+
+// PreDeleteDrain returns if the Deployment is drained before the downstream objects are removed
+func (a *Application) PreDeleteDrain() bool {
+	if a == nil || a.Spec.DeletionPolicy == nil || a.Spec.DeletionPolicy.PreDelete == nil {
+		return false
+	}
+
+	return a.Spec.DeletionPolicy.PreDelete.Drain
+}
+
+// PreDeleteTimeout returns how long the drain is waited for, and defaults to 5 minutes
+func (a *Application) PreDeleteTimeout() time.Duration {
+	if a == nil || a.Spec.DeletionPolicy == nil || a.Spec.DeletionPolicy.PreDelete == nil || a.Spec.DeletionPolicy.PreDelete.TimeoutSeconds == nil {
+		return 5 * time.Minute
+	}
+
+	return time.Duration(*a.Spec.DeletionPolicy.PreDelete.TimeoutSeconds) * time.Second
+}
+
 func (a *Application) Instancer() *string {
 	uuid := string(a.ObjectMeta.UID)
 	truncMax := 6
@@ -180,6 +596,26 @@ func (a *Application) Instancer() *string {
 	return acmeioutils.StringPointerGenerator(uuid[:truncMax])
 }
 
+// Resources has no default, the Application's container runs without requests or limits unless the operator sets them
+func (a *Application) Resources() *corev1.ResourceRequirements {
+	return nil
+}
+
+func (a *Application) Labels() map[string]string {
+	return map[string]string{}
+}
+
+func (a *Application) IngressClassName() *string {
+	return acmeioutils.StringPointerGenerator(INGRESS_CLASS)
+}
+
+func (a *Application) IngressAnnotations() map[string]string {
+	return map[string]string{
+		"alb.ingress.kubernetes.io/scheme":      "internet-facing",
+		"alb.ingress.kubernetes.io/target-type": "ip",
+	}
+}
+
 // Reduired in order to interact with the control plane
 func init() {
 	SchemeBuilder.Register(&Application{}, &ApplicationList{})
@@ -21,6 +21,7 @@ import (
 	"math/rand"
 	"reflect"
 	"testing"
+	"time"
 
 	acmeioutils "github.com/nathanbrophy/portfolio-demo/k8s/utils"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -42,7 +43,7 @@ func TestApplication_Replicas(t *testing.T) {
 		{
 			name:   "default",
 			fields: fields{},
-			want:   acmeioutils.Int32PointerGenerator(1),
+			want:   nil,
 		},
 		{
 			name: "no default",
@@ -64,7 +65,7 @@ func TestApplication_Replicas(t *testing.T) {
 				Spec:       tt.fields.Spec,
 				Status:     tt.fields.Status,
 			}
-			if got := a.Replicas(); *got != *tt.want {
+			if got := a.Replicas(); !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("Application.Replicas() = %v, want %v", got, tt.want)
 			}
 		})
@@ -380,3 +381,257 @@ func TestApplication_Instancer(t *testing.T) {
 		})
 	}
 }
+
+func TestApplication_RevisionHistoryLimit(t *testing.T) {
+	type fields struct {
+		TypeMeta   metav1.TypeMeta
+		ObjectMeta metav1.ObjectMeta
+		Spec       ApplicationSpec
+		Status     ApplicationStatus
+	}
+	tests := []struct {
+		name   string
+		fields fields
+		want   *int32
+	}{
+		{
+			name:   "default",
+			fields: fields{},
+			want:   acmeioutils.Int32PointerGenerator(10),
+		},
+		{
+			name: "no default",
+			fields: fields{
+				Spec: ApplicationSpec{
+					RevisionHistoryLimit: acmeioutils.Int32PointerGenerator(3),
+				},
+			},
+			want: acmeioutils.Int32PointerGenerator(3),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &Application{
+				TypeMeta:   tt.fields.TypeMeta,
+				ObjectMeta: tt.fields.ObjectMeta,
+				Spec:       tt.fields.Spec,
+				Status:     tt.fields.Status,
+			}
+			if got := a.RevisionHistoryLimit(); *got != *tt.want {
+				t.Errorf("Application.RevisionHistoryLimit() = %v, want %v", *got, *tt.want)
+			}
+		})
+	}
+}
+
+func TestApplication_PinImageDigest(t *testing.T) {
+	type fields struct {
+		TypeMeta   metav1.TypeMeta
+		ObjectMeta metav1.ObjectMeta
+		Spec       ApplicationSpec
+		Status     ApplicationStatus
+	}
+	pin := true
+	tests := []struct {
+		name   string
+		fields fields
+		want   bool
+	}{
+		{
+			name:   "default",
+			fields: fields{},
+			want:   false,
+		},
+		{
+			name: "no default",
+			fields: fields{
+				Spec: ApplicationSpec{
+					Application: &ApplicationApplication{
+						PinImageDigest: &pin,
+					},
+				},
+			},
+			want: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &Application{
+				TypeMeta:   tt.fields.TypeMeta,
+				ObjectMeta: tt.fields.ObjectMeta,
+				Spec:       tt.fields.Spec,
+				Status:     tt.fields.Status,
+			}
+			if got := a.PinImageDigest(); got != tt.want {
+				t.Errorf("Application.PinImageDigest() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplication_Suspend(t *testing.T) {
+	type fields struct {
+		TypeMeta   metav1.TypeMeta
+		ObjectMeta metav1.ObjectMeta
+		Spec       ApplicationSpec
+		Status     ApplicationStatus
+	}
+	tests := []struct {
+		name   string
+		fields fields
+		want   bool
+	}{
+		{
+			name:   "default",
+			fields: fields{},
+			want:   false,
+		},
+		{
+			name: "no default",
+			fields: fields{
+				Spec: ApplicationSpec{
+					Suspend: acmeioutils.BoolPointerGenerator(true),
+				},
+			},
+			want: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &Application{
+				TypeMeta:   tt.fields.TypeMeta,
+				ObjectMeta: tt.fields.ObjectMeta,
+				Spec:       tt.fields.Spec,
+				Status:     tt.fields.Status,
+			}
+			if got := a.Suspend(); got != tt.want {
+				t.Errorf("Application.Suspend() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplication_DeletionPolicyFor(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy *ApplicationDeletionPolicy
+		kind   string
+		want   DeletionPolicy
+	}{
+		{
+			name: "default",
+			kind: "Deployment",
+			want: DeletionPolicyDelete,
+		},
+		{
+			name:   "default policy",
+			policy: &ApplicationDeletionPolicy{Default: DeletionPolicyOrphan},
+			kind:   "Service",
+			want:   DeletionPolicyOrphan,
+		},
+		{
+			name: "kind policy",
+			policy: &ApplicationDeletionPolicy{
+				Default: DeletionPolicyOrphan,
+				Kinds:   []ApplicationKindDeletionPolicy{{Kind: "Ingress", Policy: DeletionPolicyRetain}},
+			},
+			kind: "Ingress",
+			want: DeletionPolicyRetain,
+		},
+		{
+			name:   "unset default policy",
+			policy: &ApplicationDeletionPolicy{Kinds: []ApplicationKindDeletionPolicy{{Kind: "ServiceAccount", Policy: DeletionPolicyRetain}}},
+			kind:   "Deployment",
+			want:   DeletionPolicyDelete,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &Application{Spec: ApplicationSpec{DeletionPolicy: tt.policy}}
+			if got := a.DeletionPolicyFor(tt.kind); got != tt.want {
+				t.Errorf("Application.DeletionPolicyFor() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplication_PreDelete(t *testing.T) {
+	tests := []struct {
+		name        string
+		policy      *ApplicationDeletionPolicy
+		wantDrain   bool
+		wantTimeout time.Duration
+	}{
+		{
+			name:        "default",
+			wantDrain:   false,
+			wantTimeout: 5 * time.Minute,
+		},
+		{
+			name: "no default",
+			policy: &ApplicationDeletionPolicy{
+				PreDelete: &ApplicationPreDelete{
+					Drain:          true,
+					TimeoutSeconds: acmeioutils.Int32PointerGenerator(30),
+				},
+			},
+			wantDrain:   true,
+			wantTimeout: 30 * time.Second,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &Application{Spec: ApplicationSpec{DeletionPolicy: tt.policy}}
+			if got := a.PreDeleteDrain(); got != tt.wantDrain {
+				t.Errorf("Application.PreDeleteDrain() = %v, want %v", got, tt.wantDrain)
+			}
+			if got := a.PreDeleteTimeout(); got != tt.wantTimeout {
+				t.Errorf("Application.PreDeleteTimeout() = %v, want %v", got, tt.wantTimeout)
+			}
+		})
+	}
+}
+
+func TestApplication_DriftPolicyFor(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy *ApplicationDriftPolicy
+		kind   string
+		want   DriftPolicy
+	}{
+		{
+			name: "default",
+			kind: "Deployment",
+			want: DriftPolicyCorrect,
+		},
+		{
+			name:   "default policy",
+			policy: &ApplicationDriftPolicy{Default: DriftPolicyReport},
+			kind:   "Service",
+			want:   DriftPolicyReport,
+		},
+		{
+			name: "kind policy",
+			policy: &ApplicationDriftPolicy{
+				Default: DriftPolicyReport,
+				Kinds:   []ApplicationKindDriftPolicy{{Kind: "Ingress", Policy: DriftPolicyIgnore}},
+			},
+			kind: "Ingress",
+			want: DriftPolicyIgnore,
+		},
+		{
+			name:   "unset default policy",
+			policy: &ApplicationDriftPolicy{Kinds: []ApplicationKindDriftPolicy{{Kind: "Deployment", Policy: DriftPolicyReport}}},
+			kind:   "ServiceAccount",
+			want:   DriftPolicyCorrect,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &Application{Spec: ApplicationSpec{DriftPolicy: tt.policy}}
+			if got := a.DriftPolicyFor(tt.kind); got != tt.want {
+				t.Errorf("Application.DriftPolicyFor() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,360 @@
+/*
+Copyright 2023 Nathan Brophy.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	acmefieldpath "github.com/nathanbrophy/portfolio-demo/k8s/fieldpath"
+	acmehibernation "github.com/nathanbrophy/portfolio-demo/k8s/hibernation"
+	acmeoverrides "github.com/nathanbrophy/portfolio-demo/k8s/overrides"
+	acmepolicy "github.com/nathanbrophy/portfolio-demo/k8s/policy"
+)
+
+// ApplicationValidator validates Applications against the operator level policy when they are admitted
+// +kubebuilder:object:generate=false
+type ApplicationValidator struct {
+	// ImagePolicy provides the operator level policy every Application image must satisfy
+	ImagePolicy acmepolicy.Source
+
+	// ExtraResources provides the operator level allowlist of the kinds an Application may embed, no kind is allowed when nil
+	ExtraResources acmepolicy.ExtraResourceSource
+
+	// Kinds are the kinds of the generated downstream objects, which along with the kinds of the extra resources are the
+	// kinds a drift or deletion policy may name, the kinds are not checked when empty
+	Kinds []string
+}
+
+// SetupWebhookWithManager registers the validating webhook for Applications with the manager
+func (r *Application) SetupWebhookWithManager(mgr ctrl.Manager, validator *ApplicationValidator) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		WithValidator(validator).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/validate-acme-io-v1beta1-application,mutating=false,failurePolicy=fail,sideEffects=None,groups=acme.io,resources=applications,verbs=create;update,versions=v1beta1,name=vapplication.kb.io,admissionReviewVersions=v1
+
+var _ webhook.CustomValidator = &ApplicationValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type
+func (v *ApplicationValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	cr, ok := obj.(*Application)
+	if !ok {
+		return nil, fmt.Errorf("expected an Application but got a %T", obj)
+	}
+
+	if err := v.validateHibernation(cr); err != nil {
+		return nil, err
+	}
+	if err := v.validateDriftIgnore(cr); err != nil {
+		return nil, err
+	}
+	if _, err := v.validateOverrides(cr, nil); err != nil {
+		return nil, err
+	}
+	if err := v.validatePolicyKinds(cr); err != nil {
+		return nil, err
+	}
+	if _, err := v.validateExtraResources(cr, nil); err != nil {
+		return nil, err
+	}
+
+	return nil, v.validateImage(cr)
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type
+func (v *ApplicationValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldCR, ok := oldObj.(*Application)
+	if !ok {
+		return nil, fmt.Errorf("expected an Application but got a %T", oldObj)
+	}
+	cr, ok := newObj.(*Application)
+	if !ok {
+		return nil, fmt.Errorf("expected an Application but got a %T", newObj)
+	}
+
+	// A deleted Application is only ever updated to carry out its deletion, such
+	// as the controller removing its finalizer, which must never be blocked by an
+	// operator config that changed since the Application was admitted.
+	if !cr.GetDeletionTimestamp().IsZero() {
+		return nil, nil
+	}
+
+	if err := v.validateHibernation(cr); err != nil {
+		return nil, err
+	}
+	if err := v.validateDriftIgnore(cr); err != nil {
+		return nil, err
+	}
+	warnings, err := v.validateOverrides(cr, oldCR)
+	if err != nil {
+		return nil, err
+	}
+	if err := v.validatePolicyKinds(cr); err != nil {
+		return nil, err
+	}
+	extraWarnings, err := v.validateExtraResources(cr, oldCR)
+	if err != nil {
+		return nil, err
+	}
+	warnings = append(warnings, extraWarnings...)
+
+	// An image admitted before the policy was tightened must not block unrelated
+	// edits, such as suspending the Application during an incident, so it is
+	// only rejected when the image itself is changed.
+	if err := v.validateImage(cr); err != nil {
+		if oldCR.Image() == cr.Image() {
+			return append(warnings, err.Error()), nil
+		}
+		return nil, err
+	}
+
+	return warnings, nil
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type
+func (v *ApplicationValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *ApplicationValidator) validateImage(cr *Application) error {
+	if v.ImagePolicy == nil {
+		return nil
+	}
+
+	if err := v.ImagePolicy.ImagePolicy().Validate(cr.Image()); err != nil {
+		return apierrors.NewInvalid(
+			GroupVersion.WithKind("Application").GroupKind(),
+			cr.GetName(),
+			field.ErrorList{field.Forbidden(field.NewPath("spec", "application", "image"), err.Error())},
+		)
+	}
+
+	return nil
+}
+
+func (v *ApplicationValidator) validateHibernation(cr *Application) error {
+	if cr.Spec.Hibernation == nil {
+		return nil
+	}
+
+	hibernation := cr.Spec.Hibernation
+	if _, err := acmehibernation.Parse(hibernation.Sleep, hibernation.Wake, hibernation.TimeZone); err != nil {
+		return apierrors.NewInvalid(
+			GroupVersion.WithKind("Application").GroupKind(),
+			cr.GetName(),
+			field.ErrorList{field.Invalid(field.NewPath("spec", "hibernation"), *hibernation, err.Error())},
+		)
+	}
+
+	return nil
+}
+
+func (v *ApplicationValidator) validateDriftIgnore(cr *Application) error {
+	errs := field.ErrorList{}
+	for i, ignore := range cr.Spec.DriftIgnore {
+		for j, path := range ignore.Paths {
+			if _, err := acmefieldpath.Parse(path); err != nil {
+				errs = append(errs, field.Invalid(field.NewPath("spec", "driftIgnore").Index(i).Child("paths").Index(j), path, err.Error()))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return apierrors.NewInvalid(GroupVersion.WithKind("Application").GroupKind(), cr.GetName(), errs)
+	}
+
+	return nil
+}
+
+// validateOverrides checks that every override parses and patches a kind generated by the controller, and checks the
+// images of the containers each patch adds or changes against the image policy.  Like the images of the extra resources,
+// an image that was admitted before the policy was tightened only raises a warning.  An image a patch copies from
+// elsewhere in the object is only known once the patch is applied, and is checked by the controller instead.
+func (v *ApplicationValidator) validateOverrides(cr, oldCR *Application) (admission.Warnings, error) {
+	var imagePolicy *acmepolicy.ImagePolicy
+	if v.ImagePolicy != nil {
+		imagePolicy = v.ImagePolicy.ImagePolicy()
+	}
+	kinds := map[string]bool{}
+	for _, kind := range v.Kinds {
+		kinds[kind] = true
+	}
+
+	admittedImages := map[string]bool{}
+	if oldCR != nil {
+		for _, override := range oldCR.Spec.Overrides {
+			if patch, err := acmeoverrides.Parse(string(override.Type), override.Patch); err == nil {
+				for _, image := range patch.Images() {
+					admittedImages[image] = true
+				}
+			}
+		}
+	}
+
+	warnings := admission.Warnings{}
+	errs := field.ErrorList{}
+	for i, override := range cr.Spec.Overrides {
+		path := field.NewPath("spec", "overrides").Index(i)
+		if len(kinds) > 0 && !kinds[override.Kind] {
+			errs = append(errs, field.Invalid(path.Child("kind"), override.Kind, "not a kind generated by the controller"))
+		}
+		patch, err := acmeoverrides.Parse(string(override.Type), override.Patch)
+		if err != nil {
+			errs = append(errs, field.Invalid(path.Child("patch"), override.Patch, err.Error()))
+			continue
+		}
+		if imagePolicy == nil {
+			continue
+		}
+		for _, image := range patch.Images() {
+			if err := imagePolicy.Validate(image); err != nil {
+				if admittedImages[image] {
+					warnings = append(warnings, fmt.Sprintf("%s: %v", path.Child("patch"), err))
+				} else {
+					errs = append(errs, field.Forbidden(path.Child("patch"), err.Error()))
+				}
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return nil, apierrors.NewInvalid(GroupVersion.WithKind("Application").GroupKind(), cr.GetName(), errs)
+	}
+
+	return warnings, nil
+}
+
+// validatePolicyKinds checks that every kind named by the drift and deletion policies of the CR is either generated
+// by the controller or the kind of one of its extra resources, as a policy of any other kind would never apply.
+func (v *ApplicationValidator) validatePolicyKinds(cr *Application) error {
+	if len(v.Kinds) == 0 {
+		return nil
+	}
+
+	kinds := map[string]bool{}
+	for _, kind := range v.Kinds {
+		kinds[kind] = true
+	}
+	for i := range cr.Spec.ExtraResources {
+		if obj, err := cr.ExtraResource(i); err == nil {
+			kinds[obj.GetKind()] = true
+		}
+	}
+
+	errs := field.ErrorList{}
+	if cr.Spec.DriftPolicy != nil {
+		for i, policy := range cr.Spec.DriftPolicy.Kinds {
+			if !kinds[policy.Kind] {
+				errs = append(errs, field.Invalid(field.NewPath("spec", "driftPolicy", "kinds").Index(i).Child("kind"), policy.Kind, "not a kind of the downstream objects"))
+			}
+		}
+	}
+	if cr.Spec.DeletionPolicy != nil {
+		for i, policy := range cr.Spec.DeletionPolicy.Kinds {
+			if !kinds[policy.Kind] {
+				errs = append(errs, field.Invalid(field.NewPath("spec", "deletionPolicy", "kinds").Index(i).Child("kind"), policy.Kind, "not a kind of the downstream objects"))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return apierrors.NewInvalid(GroupVersion.WithKind("Application").GroupKind(), cr.GetName(), errs)
+	}
+
+	return nil
+}
+
+// validateExtraResources checks the extra resources of the CR against the operator policies.  Like an image, a kind
+// or image that was admitted before the policies were tightened only raises a warning, so that the rest of the CR
+// can still be edited, and is only rejected when it is new to the CR.
+func (v *ApplicationValidator) validateExtraResources(cr, oldCR *Application) (admission.Warnings, error) {
+	var policy *acmepolicy.ExtraResourcePolicy
+	if v.ExtraResources != nil {
+		policy = v.ExtraResources.ExtraResourcePolicy()
+	}
+	var imagePolicy *acmepolicy.ImagePolicy
+	if v.ImagePolicy != nil {
+		imagePolicy = v.ImagePolicy.ImagePolicy()
+	}
+
+	admittedKinds, admittedImages := map[string]bool{}, map[string]bool{}
+	if oldCR != nil {
+		for i := range oldCR.Spec.ExtraResources {
+			if obj, err := oldCR.ExtraResource(i); err == nil {
+				admittedKinds[obj.GroupVersionKind().GroupKind().String()] = true
+				for _, image := range acmepolicy.ContainerImages(obj.Object) {
+					admittedImages[image] = true
+				}
+			}
+		}
+	}
+
+	warnings := admission.Warnings{}
+	errs := field.ErrorList{}
+	seen := map[string]bool{}
+	for i := range cr.Spec.ExtraResources {
+		path := field.NewPath("spec", "extraResources").Index(i)
+		obj, err := cr.ExtraResource(i)
+		if err != nil {
+			errs = append(errs, field.Invalid(path, string(cr.Spec.ExtraResources[i].Raw), err.Error()))
+			continue
+		}
+
+		if namespace := obj.GetNamespace(); namespace != "" && namespace != cr.GetNamespace() {
+			errs = append(errs, field.Invalid(path.Child("metadata", "namespace"), namespace, "extra resources are created in the namespace of the Application"))
+		}
+		kind := obj.GroupVersionKind().GroupKind()
+		if err := policy.Validate(kind); err != nil {
+			if admittedKinds[kind.String()] {
+				warnings = append(warnings, fmt.Sprintf("%s: %v", path.Child("kind"), err))
+			} else {
+				errs = append(errs, field.Forbidden(path.Child("kind"), err.Error()))
+			}
+		}
+		if imagePolicy != nil {
+			for _, image := range acmepolicy.ContainerImages(obj.Object) {
+				if err := imagePolicy.Validate(image); err != nil {
+					if admittedImages[image] {
+						warnings = append(warnings, fmt.Sprintf("%s: %v", path, err))
+					} else {
+						errs = append(errs, field.Forbidden(path, err.Error()))
+					}
+				}
+			}
+		}
+		key := kind.String() + "/" + obj.GetName()
+		if seen[key] {
+			errs = append(errs, field.Duplicate(path, key))
+		}
+		seen[key] = true
+	}
+	if len(errs) > 0 {
+		return nil, apierrors.NewInvalid(GroupVersion.WithKind("Application").GroupKind(), cr.GetName(), errs)
+	}
+	if len(warnings) == 0 {
+		return nil, nil
+	}
+
+	return warnings, nil
+}
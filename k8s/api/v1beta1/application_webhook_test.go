@@ -0,0 +1,397 @@
+/*
+Copyright 2023 Nathan Brophy.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"context"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	acmepolicy "github.com/nathanbrophy/portfolio-demo/k8s/policy"
+	acmeioutils "github.com/nathanbrophy/portfolio-demo/k8s/utils"
+)
+
+func generateWebhookCR(image string) *Application {
+	return &Application{
+		Spec: ApplicationSpec{
+			Application: &ApplicationApplication{
+				Image: acmeioutils.StringPointerGenerator(image),
+			},
+		},
+	}
+}
+
+func generateHibernatingCR(image string, hibernation ApplicationHibernation) *Application {
+	cr := generateWebhookCR(image)
+	cr.Spec.Hibernation = &hibernation
+
+	return cr
+}
+
+func generateDriftIgnoringCR(image string, ignore ...ApplicationDriftIgnoreRule) *Application {
+	cr := generateWebhookCR(image)
+	cr.Spec.DriftIgnore = ignore
+
+	return cr
+}
+
+func generateOverridingCR(image string, overrides ...ApplicationOverride) *Application {
+	cr := generateWebhookCR(image)
+	cr.Spec.Overrides = overrides
+
+	return cr
+}
+
+func generateExtraResourceCR(image string, extraResources ...string) *Application {
+	cr := generateWebhookCR(image)
+	cr.SetNamespace("apps")
+	for _, raw := range extraResources {
+		cr.Spec.ExtraResources = append(cr.Spec.ExtraResources, runtime.RawExtension{Raw: []byte(raw)})
+	}
+
+	return cr
+}
+
+func generatePolicyCR(image string, drift []ApplicationKindDriftPolicy, deletion []ApplicationKindDeletionPolicy, extraResources ...string) *Application {
+	cr := generateExtraResourceCR(image, extraResources...)
+	cr.Spec.DriftPolicy = &ApplicationDriftPolicy{Kinds: drift}
+	cr.Spec.DeletionPolicy = &ApplicationDeletionPolicy{Kinds: deletion}
+
+	return cr
+}
+
+func generateDeletedCR(cr *Application, finalizers ...string) *Application {
+	deleted := metav1.Now()
+	cr.SetDeletionTimestamp(&deleted)
+	cr.SetFinalizers(finalizers)
+
+	return cr
+}
+
+func TestApplicationValidator_ValidateCreate(t *testing.T) {
+	validator := &ApplicationValidator{
+		ImagePolicy: &acmepolicy.ImagePolicy{
+			AllowedRegistries: []string{"quay.io/acme"},
+			DeniedTags:        []string{"latest"},
+		},
+		ExtraResources: &acmepolicy.ExtraResourcePolicy{
+			AllowedKinds: []string{"ConfigMap", "CronJob.batch"},
+		},
+		Kinds: []string{"Deployment", "Ingress", "Service", "ServiceAccount"},
+	}
+
+	tests := []struct {
+		name    string
+		cr      *Application
+		wantErr bool
+	}{
+		{
+			name: "allowed image",
+			cr:   generateWebhookCR("quay.io/acme/app:v1.0.0"),
+		},
+		{
+			name:    "denied tag",
+			cr:      generateWebhookCR("quay.io/acme/app:latest"),
+			wantErr: true,
+		},
+		{
+			name:    "registry not allowed",
+			cr:      generateWebhookCR("example.com/app:v1.0.0"),
+			wantErr: true,
+		},
+		{
+			name: "valid hibernation window",
+			cr: generateHibernatingCR("quay.io/acme/app:v1.0.0", ApplicationHibernation{
+				Sleep:    "0 20 * * 1-5",
+				Wake:     "0 7 * * 1-5",
+				TimeZone: "Europe/Berlin",
+			}),
+		},
+		{
+			name: "invalid hibernation schedule",
+			cr: generateHibernatingCR("quay.io/acme/app:v1.0.0", ApplicationHibernation{
+				Sleep: "at night",
+				Wake:  "0 7 * * 1-5",
+			}),
+			wantErr: true,
+		},
+		{
+			name: "valid drift ignore rules",
+			cr: generateDriftIgnoringCR("quay.io/acme/app:v1.0.0", ApplicationDriftIgnoreRule{
+				Kind:  "Ingress",
+				Paths: []string{"/metadata/annotations/alb.ingress.kubernetes.io~1scheme", "metadata.annotations[alb.ingress.kubernetes.io/target-type]"},
+			}),
+		},
+		{
+			name: "invalid drift ignore path",
+			cr: generateDriftIgnoringCR("quay.io/acme/app:v1.0.0", ApplicationDriftIgnoreRule{
+				Paths: []string{"spec..replicas"},
+			}),
+			wantErr: true,
+		},
+		{
+			name: "valid overrides",
+			cr: generateOverridingCR("quay.io/acme/app:v1.0.0", ApplicationOverride{
+				Kind:  "Deployment",
+				Patch: "spec:\n  template:\n    spec:\n      priorityClassName: critical\n",
+			}, ApplicationOverride{
+				Kind:  "Service",
+				Type:  OverridePatchTypeJSON6902,
+				Patch: `[{"op": "add", "path": "/spec/externalTrafficPolicy", "value": "Local"}]`,
+			}),
+		},
+		{
+			name: "strategic merge override that is not an object",
+			cr: generateOverridingCR("quay.io/acme/app:v1.0.0", ApplicationOverride{
+				Kind:  "Deployment",
+				Patch: "- priorityClassName",
+			}),
+			wantErr: true,
+		},
+		{
+			name: "JSON6902 override with an unknown operation",
+			cr: generateOverridingCR("quay.io/acme/app:v1.0.0", ApplicationOverride{
+				Kind:  "Deployment",
+				Type:  OverridePatchTypeJSON6902,
+				Patch: `[{"op": "merge", "path": "/spec"}]`,
+			}),
+			wantErr: true,
+		},
+		{
+			name: "override of a kind not generated",
+			cr: generateOverridingCR("quay.io/acme/app:v1.0.0", ApplicationOverride{
+				Kind:  "Deploymnet",
+				Patch: "spec:\n  template:\n    spec:\n      priorityClassName: critical\n",
+			}),
+			wantErr: true,
+		},
+		{
+			name: "override adding a container with a denied image",
+			cr: generateOverridingCR("quay.io/acme/app:v1.0.0", ApplicationOverride{
+				Kind:  "Deployment",
+				Patch: "spec:\n  template:\n    spec:\n      containers:\n      - name: sidecar\n        image: example.com/sidecar:v1.0.0\n",
+			}),
+			wantErr: true,
+		},
+		{
+			name: "override changing the image to a denied one",
+			cr: generateOverridingCR("quay.io/acme/app:v1.0.0", ApplicationOverride{
+				Kind:  "Deployment",
+				Type:  OverridePatchTypeJSON6902,
+				Patch: `[{"op": "replace", "path": "/spec/template/spec/containers/0/image", "value": "quay.io/acme/app:latest"}]`,
+			}),
+			wantErr: true,
+		},
+		{
+			name: "allowed extra resources",
+			cr: generateExtraResourceCR("quay.io/acme/app:v1.0.0",
+				`{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "settings"}, "data": {"mode": "fast"}}`,
+				`{"apiVersion": "batch/v1", "kind": "CronJob", "metadata": {"name": "cleanup", "namespace": "apps"}}`,
+			),
+		},
+		{
+			name: "extra resource with an image the policy denies",
+			cr: generateExtraResourceCR("quay.io/acme/app:v1.0.0",
+				`{"apiVersion": "batch/v1", "kind": "CronJob", "metadata": {"name": "cleanup"}, "spec": {"jobTemplate": {"spec": {"template": {"spec": {"containers": [{"name": "cleanup", "image": "example.com/cleanup:v1.0.0"}]}}}}}}`,
+			),
+			wantErr: true,
+		},
+		{
+			name: "extra resource of a kind not allowed",
+			cr: generateExtraResourceCR("quay.io/acme/app:v1.0.0",
+				`{"apiVersion": "rbac.authorization.k8s.io/v1", "kind": "Role", "metadata": {"name": "admin"}}`,
+			),
+			wantErr: true,
+		},
+		{
+			name: "extra resource in another namespace",
+			cr: generateExtraResourceCR("quay.io/acme/app:v1.0.0",
+				`{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "settings", "namespace": "kube-system"}}`,
+			),
+			wantErr: true,
+		},
+		{
+			name: "extra resource without a name",
+			cr: generateExtraResourceCR("quay.io/acme/app:v1.0.0",
+				`{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"generateName": "settings-"}}`,
+			),
+			wantErr: true,
+		},
+		{
+			name: "extra resource without a kind",
+			cr: generateExtraResourceCR("quay.io/acme/app:v1.0.0",
+				`{"apiVersion": "v1", "metadata": {"name": "settings"}}`,
+			),
+			wantErr: true,
+		},
+		{
+			name: "duplicate extra resources",
+			cr: generateExtraResourceCR("quay.io/acme/app:v1.0.0",
+				`{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "settings"}}`,
+				`{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "settings"}}`,
+			),
+			wantErr: true,
+		},
+		{
+			name: "policies of generated and extra resource kinds",
+			cr: generatePolicyCR("quay.io/acme/app:v1.0.0",
+				[]ApplicationKindDriftPolicy{{Kind: "Deployment", Policy: DriftPolicyReport}, {Kind: "ConfigMap", Policy: DriftPolicyIgnore}},
+				[]ApplicationKindDeletionPolicy{{Kind: "ServiceAccount", Policy: DeletionPolicyRetain}},
+				`{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "settings"}}`,
+			),
+		},
+		{
+			name: "drift policy of an unknown kind",
+			cr: generatePolicyCR("quay.io/acme/app:v1.0.0",
+				[]ApplicationKindDriftPolicy{{Kind: "deployment", Policy: DriftPolicyReport}},
+				nil,
+			),
+			wantErr: true,
+		},
+		{
+			name: "deletion policy of a kind without an extra resource",
+			cr: generatePolicyCR("quay.io/acme/app:v1.0.0",
+				nil,
+				[]ApplicationKindDeletionPolicy{{Kind: "ConfigMap", Policy: DeletionPolicyOrphan}},
+			),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := validator.ValidateCreate(context.Background(), tt.cr); (err != nil) != tt.wantErr {
+				t.Errorf("ApplicationValidator.ValidateCreate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestApplicationValidator_ValidateUpdate(t *testing.T) {
+	configMap := `{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "settings"}}`
+	cronJob := func(image string) string {
+		return `{"apiVersion": "batch/v1", "kind": "CronJob", "metadata": {"name": "cleanup"}, "spec": {"jobTemplate": {"spec": {"template": {"spec": {"containers": [{"name": "cleanup", "image": "` + image + `"}]}}}}}}`
+	}
+	sidecar := func(image string) ApplicationOverride {
+		return ApplicationOverride{
+			Kind:  "Deployment",
+			Patch: "spec:\n  template:\n    spec:\n      containers:\n      - name: sidecar\n        image: " + image + "\n",
+		}
+	}
+	validator := &ApplicationValidator{
+		ImagePolicy: &acmepolicy.ImagePolicy{
+			DeniedTags: []string{"latest"},
+		},
+	}
+
+	tests := []struct {
+		name         string
+		oldCR        *Application
+		cr           *Application
+		wantErr      bool
+		wantWarnings bool
+	}{
+		{
+			name:  "allowed image",
+			oldCR: generateWebhookCR("quay.io/acme/app:latest"),
+			cr:    generateWebhookCR("quay.io/acme/app:v1.0.0"),
+		},
+		{
+			name:    "changed to a denied image",
+			oldCR:   generateWebhookCR("quay.io/acme/app:v1.0.0"),
+			cr:      generateWebhookCR("quay.io/acme/app:latest"),
+			wantErr: true,
+		},
+		{
+			name:         "unchanged denied image",
+			oldCR:        generateWebhookCR("quay.io/acme/app:latest"),
+			cr:           generateWebhookCR("quay.io/acme/app:latest"),
+			wantWarnings: true,
+		},
+		{
+			name:  "invalid override",
+			oldCR: generateWebhookCR("quay.io/acme/app:v1.0.0"),
+			cr: generateOverridingCR("quay.io/acme/app:v1.0.0", ApplicationOverride{
+				Kind:  "Deployment",
+				Type:  OverridePatchTypeJSON6902,
+				Patch: `{"spec": {}}`,
+			}),
+			wantErr: true,
+		},
+		{
+			name:         "override with a denied image admitted before",
+			oldCR:        generateOverridingCR("quay.io/acme/app:v1.0.0", sidecar("quay.io/acme/sidecar:latest")),
+			cr:           generateOverridingCR("quay.io/acme/app:v1.0.1", sidecar("quay.io/acme/sidecar:latest")),
+			wantWarnings: true,
+		},
+		{
+			name:    "override changed to a denied image",
+			oldCR:   generateOverridingCR("quay.io/acme/app:v1.0.0", sidecar("quay.io/acme/sidecar:v1.0.0")),
+			cr:      generateOverridingCR("quay.io/acme/app:v1.0.0", sidecar("quay.io/acme/sidecar:latest")),
+			wantErr: true,
+		},
+		{
+			name:  "extra resources without an allowlist",
+			oldCR: generateWebhookCR("quay.io/acme/app:v1.0.0"),
+			cr: generateExtraResourceCR("quay.io/acme/app:v1.0.0",
+				`{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "settings"}}`,
+			),
+			wantErr: true,
+		},
+		{
+			name:         "extra resource admitted before its kind was disallowed",
+			oldCR:        generateExtraResourceCR("quay.io/acme/app:v1.0.0", configMap),
+			cr:           generateExtraResourceCR("quay.io/acme/app:v1.0.1", configMap),
+			wantWarnings: true,
+		},
+		{
+			name:         "extra resource with a denied image admitted before",
+			oldCR:        generateExtraResourceCR("quay.io/acme/app:v1.0.0", cronJob("quay.io/acme/cleanup:latest")),
+			cr:           generateExtraResourceCR("quay.io/acme/app:v1.0.1", cronJob("quay.io/acme/cleanup:latest")),
+			wantWarnings: true,
+		},
+		{
+			name:    "extra resource of a kind that is not allowed added",
+			oldCR:   generateExtraResourceCR("quay.io/acme/app:v1.0.0", cronJob("quay.io/acme/cleanup:v1.0.0")),
+			cr:      generateExtraResourceCR("quay.io/acme/app:v1.0.0", cronJob("quay.io/acme/cleanup:v1.0.0"), configMap),
+			wantErr: true,
+		},
+		{
+			name:    "extra resource changed to a denied image",
+			oldCR:   generateExtraResourceCR("quay.io/acme/app:v1.0.0", cronJob("quay.io/acme/cleanup:v1.0.0")),
+			cr:      generateExtraResourceCR("quay.io/acme/app:v1.0.0", cronJob("quay.io/acme/cleanup:latest")),
+			wantErr: true,
+		},
+		{
+			name:  "finalizer removed from a deleted Application",
+			oldCR: generateDeletedCR(generateExtraResourceCR("quay.io/acme/app:latest", configMap), "acme.io/finalizer"),
+			cr:    generateDeletedCR(generateExtraResourceCR("quay.io/acme/app:latest", configMap)),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			warnings, err := validator.ValidateUpdate(context.Background(), tt.oldCR, tt.cr)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ApplicationValidator.ValidateUpdate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if (len(warnings) > 0) != tt.wantWarnings {
+				t.Errorf("ApplicationValidator.ValidateUpdate() warnings = %v, wantWarnings %v", warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
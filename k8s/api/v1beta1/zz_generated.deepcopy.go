@@ -22,7 +22,8 @@ limitations under the License.
 package v1beta1
 
 import (
-	runtime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
@@ -31,7 +32,7 @@ func (in *Application) DeepCopyInto(out *Application) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Application.
@@ -70,6 +71,11 @@ func (in *ApplicationApplication) DeepCopyInto(out *ApplicationApplication) {
 		*out = new(int32)
 		**out = **in
 	}
+	if in.PinImageDigest != nil {
+		in, out := &in.PinImageDigest, &out.PinImageDigest
+		*out = new(bool)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApplicationApplication.
@@ -117,6 +123,167 @@ func (in *ApplicationBoilerPlate) DeepCopy() *ApplicationBoilerPlate {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApplicationChildStatus) DeepCopyInto(out *ApplicationChildStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApplicationChildStatus.
+func (in *ApplicationChildStatus) DeepCopy() *ApplicationChildStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ApplicationChildStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApplicationDeletionPolicy) DeepCopyInto(out *ApplicationDeletionPolicy) {
+	*out = *in
+	if in.Kinds != nil {
+		in, out := &in.Kinds, &out.Kinds
+		*out = make([]ApplicationKindDeletionPolicy, len(*in))
+		copy(*out, *in)
+	}
+	if in.PreDelete != nil {
+		in, out := &in.PreDelete, &out.PreDelete
+		*out = new(ApplicationPreDelete)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApplicationDeletionPolicy.
+func (in *ApplicationDeletionPolicy) DeepCopy() *ApplicationDeletionPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(ApplicationDeletionPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApplicationDriftDifference) DeepCopyInto(out *ApplicationDriftDifference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApplicationDriftDifference.
+func (in *ApplicationDriftDifference) DeepCopy() *ApplicationDriftDifference {
+	if in == nil {
+		return nil
+	}
+	out := new(ApplicationDriftDifference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApplicationDriftIgnoreRule) DeepCopyInto(out *ApplicationDriftIgnoreRule) {
+	*out = *in
+	if in.Paths != nil {
+		in, out := &in.Paths, &out.Paths
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApplicationDriftIgnoreRule.
+func (in *ApplicationDriftIgnoreRule) DeepCopy() *ApplicationDriftIgnoreRule {
+	if in == nil {
+		return nil
+	}
+	out := new(ApplicationDriftIgnoreRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApplicationDriftPolicy) DeepCopyInto(out *ApplicationDriftPolicy) {
+	*out = *in
+	if in.Kinds != nil {
+		in, out := &in.Kinds, &out.Kinds
+		*out = make([]ApplicationKindDriftPolicy, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApplicationDriftPolicy.
+func (in *ApplicationDriftPolicy) DeepCopy() *ApplicationDriftPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(ApplicationDriftPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApplicationDriftRecord) DeepCopyInto(out *ApplicationDriftRecord) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+	if in.Differences != nil {
+		in, out := &in.Differences, &out.Differences
+		*out = make([]ApplicationDriftDifference, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApplicationDriftRecord.
+func (in *ApplicationDriftRecord) DeepCopy() *ApplicationDriftRecord {
+	if in == nil {
+		return nil
+	}
+	out := new(ApplicationDriftRecord)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApplicationHibernation) DeepCopyInto(out *ApplicationHibernation) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApplicationHibernation.
+func (in *ApplicationHibernation) DeepCopy() *ApplicationHibernation {
+	if in == nil {
+		return nil
+	}
+	out := new(ApplicationHibernation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApplicationKindDeletionPolicy) DeepCopyInto(out *ApplicationKindDeletionPolicy) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApplicationKindDeletionPolicy.
+func (in *ApplicationKindDeletionPolicy) DeepCopy() *ApplicationKindDeletionPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(ApplicationKindDeletionPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApplicationKindDriftPolicy) DeepCopyInto(out *ApplicationKindDriftPolicy) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApplicationKindDriftPolicy.
+func (in *ApplicationKindDriftPolicy) DeepCopy() *ApplicationKindDriftPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(ApplicationKindDriftPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ApplicationList) DeepCopyInto(out *ApplicationList) {
 	*out = *in
@@ -149,6 +316,56 @@ func (in *ApplicationList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApplicationOverride) DeepCopyInto(out *ApplicationOverride) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApplicationOverride.
+func (in *ApplicationOverride) DeepCopy() *ApplicationOverride {
+	if in == nil {
+		return nil
+	}
+	out := new(ApplicationOverride)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApplicationPreDelete) DeepCopyInto(out *ApplicationPreDelete) {
+	*out = *in
+	if in.TimeoutSeconds != nil {
+		in, out := &in.TimeoutSeconds, &out.TimeoutSeconds
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApplicationPreDelete.
+func (in *ApplicationPreDelete) DeepCopy() *ApplicationPreDelete {
+	if in == nil {
+		return nil
+	}
+	out := new(ApplicationPreDelete)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApplicationRollback) DeepCopyInto(out *ApplicationRollback) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApplicationRollback.
+func (in *ApplicationRollback) DeepCopy() *ApplicationRollback {
+	if in == nil {
+		return nil
+	}
+	out := new(ApplicationRollback)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ApplicationSpec) DeepCopyInto(out *ApplicationSpec) {
 	*out = *in
@@ -162,6 +379,55 @@ func (in *ApplicationSpec) DeepCopyInto(out *ApplicationSpec) {
 		*out = new(ApplicationBoilerPlate)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.RevisionHistoryLimit != nil {
+		in, out := &in.RevisionHistoryLimit, &out.RevisionHistoryLimit
+		*out = new(int32)
+		**out = **in
+	}
+	if in.RollbackTo != nil {
+		in, out := &in.RollbackTo, &out.RollbackTo
+		*out = new(ApplicationRollback)
+		**out = **in
+	}
+	if in.Suspend != nil {
+		in, out := &in.Suspend, &out.Suspend
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Hibernation != nil {
+		in, out := &in.Hibernation, &out.Hibernation
+		*out = new(ApplicationHibernation)
+		**out = **in
+	}
+	if in.DeletionPolicy != nil {
+		in, out := &in.DeletionPolicy, &out.DeletionPolicy
+		*out = new(ApplicationDeletionPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DriftPolicy != nil {
+		in, out := &in.DriftPolicy, &out.DriftPolicy
+		*out = new(ApplicationDriftPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DriftIgnore != nil {
+		in, out := &in.DriftIgnore, &out.DriftIgnore
+		*out = make([]ApplicationDriftIgnoreRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Overrides != nil {
+		in, out := &in.Overrides, &out.Overrides
+		*out = make([]ApplicationOverride, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExtraResources != nil {
+		in, out := &in.ExtraResources, &out.ExtraResources
+		*out = make([]runtime.RawExtension, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApplicationSpec.
@@ -177,6 +443,29 @@ func (in *ApplicationSpec) DeepCopy() *ApplicationSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ApplicationStatus) DeepCopyInto(out *ApplicationStatus) {
 	*out = *in
+	if in.NextHibernationTransition != nil {
+		in, out := &in.NextHibernationTransition, &out.NextHibernationTransition
+		*out = (*in).DeepCopy()
+	}
+	if in.Children != nil {
+		in, out := &in.Children, &out.Children
+		*out = make([]ApplicationChildStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.RecentDrift != nil {
+		in, out := &in.RecentDrift, &out.RecentDrift
+		*out = make([]ApplicationDriftRecord, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApplicationStatus.
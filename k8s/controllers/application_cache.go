@@ -0,0 +1,74 @@
+/*
+Copyright 2023 Nathan Brophy.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	acmegenerators "github.com/nathanbrophy/portfolio-demo/k8s/generators"
+	acmerevisions "github.com/nathanbrophy/portfolio-demo/k8s/revisions"
+)
+
+// CacheByObject scopes the cache of the manager to the downstream objects labelled as managed by the controller, and
+// to the ControllerRevisions recorded for an Application, so that the manager does not hold every object of those
+// kinds in the cluster in memory.  Other kinds the controller reads, such as the pull secrets of an Application and the
+// Endpoints of its Service, are read through the API reader and never cached.
+func CacheByObject(registry *acmegenerators.Registry) map[client.Object]cache.ByObject {
+	managed := cache.ByObject{
+		Label: labels.SelectorFromSet(labels.Set{acmegenerators.ManagedByLabel: acmegenerators.ManagedBy}),
+	}
+
+	byObject := map[client.Object]cache.ByObject{}
+	for _, registration := range registry.Registrations() {
+		byObject[registration.New()] = managed
+	}
+
+	recorded, _ := labels.NewRequirement(acmerevisions.ApplicationLabel, selection.Exists, nil)
+	byObject[&appsv1.ControllerRevision{}] = cache.ByObject{Label: labels.NewSelector().Add(*recorded)}
+
+	return byObject
+}
+
+// reader is the reader for the objects the cache of the manager leaves out, which reads straight from the API server
+// when the controller has an API reader
+func (r *ApplicationReconciler) reader() client.Reader {
+	if r.APIReader != nil {
+		return r.APIReader
+	}
+
+	return r.Client
+}
+
+// getChild loads the cluster state of a downstream object.  The cache only holds the objects labelled as managed by
+// the controller, so an object missing from the cache is looked up on the API server before it is taken as missing.
+// An existing object without the label, such as one marked for adoption or one the CR does not control, is then
+// never mistaken for an object to create.
+func (r *ApplicationReconciler) getChild(ctx context.Context, obj client.Object) error {
+	err := r.Client.Get(ctx, client.ObjectKeyFromObject(obj), obj)
+	if errors.IsNotFound(err) && r.APIReader != nil {
+		return r.APIReader.Get(ctx, client.ObjectKeyFromObject(obj), obj)
+	}
+
+	return err
+}
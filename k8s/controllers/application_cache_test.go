@@ -0,0 +1,139 @@
+/*
+Copyright 2023 Nathan Brophy.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	acmegenerators "github.com/nathanbrophy/portfolio-demo/k8s/generators"
+	acmerevisions "github.com/nathanbrophy/portfolio-demo/k8s/revisions"
+	acmetest "github.com/nathanbrophy/portfolio-demo/k8s/utils/test"
+)
+
+func TestCacheByObject(t *testing.T) {
+	byObject := CacheByObject(acmegenerators.DefaultRegistry)
+
+	for _, reconcilers := range manifests(acmegenerators.DefaultRegistry, acmetest.GenerateCRWithDefaults()) {
+		var selector labels.Selector
+		for obj, options := range byObject {
+			if reflect.TypeOf(obj) == reflect.TypeOf(reconcilers.ObjectLoader) {
+				selector = options.Label
+			}
+		}
+
+		kind := reflect.TypeOf(reconcilers.ObjectLoader).Elem().Name()
+		if selector == nil {
+			t.Errorf("CacheByObject(acmegenerators.DefaultRegistry) does not scope the cache of %s", kind)
+			continue
+		}
+		if !selector.Matches(labels.Set(reconcilers.Manifest.GetLabels())) {
+			t.Errorf("CacheByObject(acmegenerators.DefaultRegistry) selector %s does not match the generated %s", selector, kind)
+		}
+	}
+
+	var options cache.ByObject
+	ok := false
+	for obj, found := range byObject {
+		if _, isRevision := obj.(*appsv1.ControllerRevision); isRevision {
+			options, ok = found, true
+		}
+	}
+	if !ok || options.Label == nil {
+		t.Fatalf("CacheByObject(acmegenerators.DefaultRegistry) does not scope the cache of ControllerRevision")
+	}
+	rev, err := acmerevisions.New(reconcilingCR("cached"), 1)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if !options.Label.Matches(labels.Set(rev.GetLabels())) {
+		t.Errorf("CacheByObject(acmegenerators.DefaultRegistry) selector %s does not match a recorded revision", options.Label)
+	}
+	if options.Label.Matches(labels.Set{"app": "other"}) {
+		t.Errorf("CacheByObject(acmegenerators.DefaultRegistry) selector %s matches a revision of another controller", options.Label)
+	}
+}
+
+func TestGetChild(t *testing.T) {
+	unlabelled := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "default",
+			Name:        "acme-application",
+			Annotations: map[string]string{AdoptAnnotation: "application-sample"},
+		},
+	}
+
+	tests := []struct {
+		name         string
+		cached       []client.Object
+		live         []client.Object
+		noAPIReader  bool
+		wantNotFound bool
+	}{
+		{
+			name:   "cached",
+			cached: []client.Object{unlabelled.DeepCopy()},
+		},
+		{
+			name: "left out of the cache",
+			live: []client.Object{unlabelled.DeepCopy()},
+		},
+		{
+			name:         "missing",
+			wantNotFound: true,
+		},
+		{
+			name:         "no API reader",
+			live:         []client.Object{unlabelled.DeepCopy()},
+			noAPIReader:  true,
+			wantNotFound: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &ApplicationReconciler{
+				Client:    fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(tt.cached...).Build(),
+				APIReader: fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(tt.live...).Build(),
+			}
+			if tt.noAPIReader {
+				r.APIReader = nil
+			}
+
+			found := &corev1.Service{}
+			found.SetNamespace(unlabelled.GetNamespace())
+			found.SetName(unlabelled.GetName())
+			err := r.getChild(context.Background(), found)
+			if errors.IsNotFound(err) != tt.wantNotFound {
+				t.Fatalf("getChild() error = %v, wantNotFound %v", err, tt.wantNotFound)
+			}
+			if !tt.wantNotFound && found.GetAnnotations()[AdoptAnnotation] != "application-sample" {
+				t.Errorf("getChild() = %v, want the object marked for adoption", found)
+			}
+		})
+	}
+}
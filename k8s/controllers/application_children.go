@@ -0,0 +1,49 @@
+/*
+Copyright 2023 Nathan Brophy.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	acmeiov1beta1 "github.com/nathanbrophy/portfolio-demo/k8s/api/v1beta1"
+)
+
+// Reasons reported in the status of a downstream object after it was reconciled
+const (
+	childReasonCreated           string = "Created"
+	childReasonUpdated           string = "Updated"
+	childReasonAdopted           string = "Adopted"
+	childReasonInSync            string = "InSync"
+	childReasonDrifted           string = "Drifted"
+	childReasonDriftIgnored      string = "DriftIgnored"
+	childReasonOwnershipConflict string = "OwnershipConflict"
+	childReasonFailed            string = "Failed"
+)
+
+// withChildren records the outcome of reconciling every downstream object in the status of the CR
+func withChildren(children []acmeiov1beta1.ApplicationChildStatus) statusMutator {
+	return func(status *acmeiov1beta1.ApplicationStatus) {
+		status.Children = children
+	}
+}
+
+// failedChild marks the status of a downstream object as failed with the given error
+func failedChild(child acmeiov1beta1.ApplicationChildStatus, err error) acmeiov1beta1.ApplicationChildStatus {
+	child.Synced = false
+	child.Reason = childReasonFailed
+	child.Message = err.Error()
+
+	return child
+}
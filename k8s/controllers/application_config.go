@@ -0,0 +1,78 @@
+/*
+Copyright 2023 Nathan Brophy.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	corev1 "k8s.io/api/core/v1"
+
+	acmeapi "github.com/nathanbrophy/portfolio-demo/k8s/api"
+	acmeoperatorconfig "github.com/nathanbrophy/portfolio-demo/k8s/operatorconfig"
+)
+
+// defaultsOverride decorates the CR so that the generators render the operator defaults in place of the built in
+// defaults of the API
+type defaultsOverride struct {
+	acmeapi.Application
+	defaults acmeoperatorconfig.Defaults
+}
+
+func (d *defaultsOverride) Resources() *corev1.ResourceRequirements {
+	if d.defaults.Resources != nil {
+		return d.defaults.Resources.DeepCopy()
+	}
+
+	return d.Application.Resources()
+}
+
+func (d *defaultsOverride) Labels() map[string]string {
+	labels := map[string]string{}
+	for k, v := range d.defaults.Labels {
+		labels[k] = v
+	}
+	for k, v := range d.Application.Labels() {
+		labels[k] = v
+	}
+
+	return labels
+}
+
+func (d *defaultsOverride) IngressClassName() *string {
+	if d.defaults.Ingress.ClassName != nil {
+		className := *d.defaults.Ingress.ClassName
+		return &className
+	}
+
+	return d.Application.IngressClassName()
+}
+
+func (d *defaultsOverride) IngressAnnotations() map[string]string {
+	if d.defaults.Ingress.Annotations == nil {
+		return d.Application.IngressAnnotations()
+	}
+
+	annotations := map[string]string{}
+	for k, v := range d.defaults.Ingress.Annotations {
+		annotations[k] = v
+	}
+
+	return annotations
+}
+
+// withDefaults applies the operator defaults to the CR
+func withDefaults(in acmeapi.Application, defaults acmeoperatorconfig.Defaults) acmeapi.Application {
+	return &defaultsOverride{Application: in, defaults: defaults}
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2023 Nathan Brophy.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"reflect"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	acmegenerators "github.com/nathanbrophy/portfolio-demo/k8s/generators"
+	acmeoperatorconfig "github.com/nathanbrophy/portfolio-demo/k8s/operatorconfig"
+	acmetest "github.com/nathanbrophy/portfolio-demo/k8s/utils/test"
+)
+
+func TestWithDefaults(t *testing.T) {
+	nginx := "nginx"
+	tests := []struct {
+		name            string
+		defaults        acmeoperatorconfig.Defaults
+		wantResources   corev1.ResourceRequirements
+		wantClass       string
+		wantAnnotations map[string]string
+		wantTeam        string
+	}{
+		{
+			name:      "no defaults",
+			wantClass: "alb",
+			wantAnnotations: map[string]string{
+				"alb.ingress.kubernetes.io/scheme":      "internet-facing",
+				"alb.ingress.kubernetes.io/target-type": "ip",
+			},
+		},
+		{
+			name: "operator defaults",
+			defaults: acmeoperatorconfig.Defaults{
+				Resources: &corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")},
+				},
+				Labels: map[string]string{
+					"team":                        "payments",
+					acmegenerators.ManagedByLabel: "someone-else",
+				},
+				Ingress: acmeoperatorconfig.IngressDefaults{
+					ClassName:   &nginx,
+					Annotations: map[string]string{"nginx.ingress.kubernetes.io/ssl-redirect": "true"},
+				},
+			},
+			wantResources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")},
+			},
+			wantClass:       "nginx",
+			wantAnnotations: map[string]string{"nginx.ingress.kubernetes.io/ssl-redirect": "true"},
+			wantTeam:        "payments",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := withDefaults(acmetest.GenerateCRWithDefaults(), tt.defaults)
+
+			for _, reconcilers := range manifests(acmegenerators.DefaultRegistry, app) {
+				labels := reconcilers.Manifest.GetLabels()
+				if labels["team"] != tt.wantTeam {
+					t.Errorf("%T team label = %q, want %q", reconcilers.Manifest, labels["team"], tt.wantTeam)
+				}
+				if labels[acmegenerators.ManagedByLabel] != acmegenerators.ManagedBy {
+					t.Errorf("%T managed-by label = %q, want it kept", reconcilers.Manifest, labels[acmegenerators.ManagedByLabel])
+				}
+
+				switch manifest := reconcilers.Manifest.(type) {
+				case *appsv1.Deployment:
+					if _, found := manifest.Spec.Selector.MatchLabels["team"]; found {
+						t.Errorf("Deployment selector = %v, want the default labels left out", manifest.Spec.Selector.MatchLabels)
+					}
+					if got := manifest.Spec.Template.Labels["team"]; got != tt.wantTeam {
+						t.Errorf("Deployment template team label = %q, want %q", got, tt.wantTeam)
+					}
+					if got := manifest.Spec.Template.Spec.Containers[0].Resources; !reflect.DeepEqual(got, tt.wantResources) {
+						t.Errorf("Deployment resources = %v, want %v", got, tt.wantResources)
+					}
+				case *networkingv1.Ingress:
+					if got := *manifest.Spec.IngressClassName; got != tt.wantClass {
+						t.Errorf("Ingress class = %q, want %q", got, tt.wantClass)
+					}
+					if !reflect.DeepEqual(manifest.GetAnnotations(), tt.wantAnnotations) {
+						t.Errorf("Ingress annotations = %v, want %v", manifest.GetAnnotations(), tt.wantAnnotations)
+					}
+				}
+			}
+		})
+	}
+}
@@ -20,25 +20,39 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+	"sync"
 	"time"
 
-	appsv1 "k8s.io/api/apps/v1"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	corev1 "k8s.io/api/core/v1"
-	networkingv1 "k8s.io/api/networking/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 
 	"github.com/go-logr/logr"
+	acmeapi "github.com/nathanbrophy/portfolio-demo/k8s/api"
 	acmeiov1beta1 "github.com/nathanbrophy/portfolio-demo/k8s/api/v1beta1"
 	acmegdrift "github.com/nathanbrophy/portfolio-demo/k8s/driftDetection"
 	acmegenerators "github.com/nathanbrophy/portfolio-demo/k8s/generators"
+	acmeoperatorconfig "github.com/nathanbrophy/portfolio-demo/k8s/operatorconfig"
+	acmeregistry "github.com/nathanbrophy/portfolio-demo/k8s/registry"
 )
 
+// FieldOwner is the field manager the controller applies the downstream objects with
+const FieldOwner string = "acme-controller"
+
 type ReconcileWrapper struct {
 	Driftor      acmegdrift.DriftDetectionFunc
 	Manifest     client.Object
@@ -48,7 +62,112 @@ type ReconcileWrapper struct {
 // ApplicationReconciler reconciles a Application object
 type ApplicationReconciler struct {
 	client.Client
-	Scheme *runtime.Scheme
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+
+	// APIReader reads straight from the API server, for the downstream objects the cache of the manager leaves out
+	APIReader client.Reader
+
+	// MaxConcurrentReconciles is the number of Applications that are reconciled at the same time
+	MaxConcurrentReconciles int
+
+	// Resolver resolves images to digests for Applications that pin their image digest
+	Resolver acmeregistry.Resolver
+
+	// Generators is the registry of the downstream objects generated for every Application, the generators of the
+	// generators package are used when nil
+	Generators *acmegenerators.Registry
+
+	// Config holds the operator config in force, which is read once per reconciliation so that a reloaded config
+	// applies from the next reconciliation on
+	Config *acmeoperatorconfig.Store
+
+	// ResyncPeriod is the longest an Application goes without being reconciled, so that changes that raise no
+	// event, such as an edit to an object the Application does not control, are still picked up.  Zero disables it.
+	ResyncPeriod time.Duration
+
+	// Backoff is how long a failed reconciliation waits before it is retried
+	Backoff Backoff
+
+	// rollouts holds the start of every Deployment rollout still in progress, by Application, to measure its duration
+	rollouts sync.Map
+}
+
+// statusMutator applies an additional change to the status of the CR as part of a status update
+type statusMutator func(*acmeiov1beta1.ApplicationStatus)
+
+// withObservedGeneration records the generation of the spec of the CR the status is reconciled from
+func withObservedGeneration(generation int64) statusMutator {
+	return func(status *acmeiov1beta1.ApplicationStatus) {
+		status.ObservedGeneration = generation
+	}
+}
+
+// withCondition sets a condition in the status of the CR, the transition time is only moved when the condition status changes
+func withCondition(condition metav1.Condition) statusMutator {
+	return func(status *acmeiov1beta1.ApplicationStatus) {
+		meta.SetStatusCondition(&status.Conditions, condition)
+	}
+}
+
+// manifests generates the downstream manifests for the CR from every registered generator that applies to it,
+// along with the means to load and compare their cluster state
+func manifests(registry *acmegenerators.Registry, app acmeapi.Application) []ReconcileWrapper {
+	toReconcile := []ReconcileWrapper{}
+	for _, registration := range registry.Registrations() {
+		if !registration.AppliesTo(app) {
+			continue
+		}
+
+		manifest := registration.Generator.Object(app)
+		manifest.GetObjectKind().SetGroupVersionKind(registration.GVK)
+		toReconcile = append(toReconcile, ReconcileWrapper{
+			Driftor:      registration.Driftor,
+			Manifest:     manifest,
+			ObjectLoader: registration.New(),
+		})
+	}
+
+	return toReconcile
+}
+
+// generators returns the registry of the downstream objects to reconcile, which defaults to every generator of the
+// generators package
+func (r *ApplicationReconciler) generators() *acmegenerators.Registry {
+	if r.Generators == nil {
+		return acmegenerators.DefaultRegistry
+	}
+
+	return r.Generators
+}
+
+// ignoring leaves the fields ignored by the rules out of the drift detection of every manifest
+func ignoring(toReconcile []ReconcileWrapper, rules acmegdrift.IgnoreRules) []ReconcileWrapper {
+	if len(rules) == 0 {
+		return toReconcile
+	}
+
+	for i := range toReconcile {
+		toReconcile[i].Driftor = acmegdrift.Ignoring(toReconcile[i].Driftor, rules)
+	}
+
+	return toReconcile
+}
+
+// apply server side applies a generated manifest as the controller's field manager.  Conflicts are forced, as the
+// fields the controller generates are the fields it is responsible for, whoever changed them last.
+func (r *ApplicationReconciler) apply(ctx context.Context, obj client.Object) error {
+	return r.Client.Patch(ctx, obj, client.Apply, client.FieldOwner(FieldOwner), client.ForceOwnership)
+}
+
+// requeueWithin makes sure the result is requeued within the given period, an earlier requeue is kept as is, and a
+// zero period leaves the result unchanged
+func requeueWithin(result ctrl.Result, period time.Duration) ctrl.Result {
+	if period > 0 && (result.RequeueAfter == 0 || result.RequeueAfter > period) {
+		result.RequeueAfter = period
+	}
+
+	return result
 }
 
 func gvk(obj client.Object) schema.GroupVersionKind {
@@ -61,6 +180,7 @@ func (r *ApplicationReconciler) updateStatus(
 	req ctrl.Request,
 	progressing bool,
 	err error,
+	mutators ...statusMutator,
 ) error {
 	found := &acmeiov1beta1.Application{}
 	_ = r.Client.Get(ctx, client.ObjectKey{Namespace: req.Namespace, Name: req.Name}, found)
@@ -79,11 +199,22 @@ func (r *ApplicationReconciler) updateStatus(
 		newStatus.Reason = fmt.Sprintf("failed to reconcile cluster state due to error: %v", err)
 	}
 
+	for _, mutate := range mutators {
+		mutate(newStatus)
+	}
+	// A progressing status is only written on the way to the outcome of the
+	// reconciliation, recording it would drop the readiness of a healthy CR
+	// on every resync and watch event until the outcome is written.
+	if !newStatus.Progressing {
+		observeStatus(req.NamespacedName, newStatus)
+	}
+
 	// A deep equal reflection is required to prevent an infinite reconciliation loop from occuring.
 	//
 	// Since the status is managed as a subresource of the API we are required to access it
 	// through the subresource .Status() mutator to propogate changes.
 	if !reflect.DeepEqual(found.Status, *newStatus) {
+		previous := found.Status.DeepCopy()
 		found.Status = *newStatus
 		if err := r.Status().Update(ctx, found); err != nil {
 			if errors.IsTooManyRequests(err) || errors.IsConflict(err) {
@@ -91,6 +222,7 @@ func (r *ApplicationReconciler) updateStatus(
 			}
 			return err
 		}
+		r.recordTransitions(found, previous, newStatus)
 	}
 
 	return nil
@@ -99,9 +231,11 @@ func (r *ApplicationReconciler) updateStatus(
 //+kubebuilder:rbac:groups=acme.io,resources=applications,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=acme.io,resources=applications/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=acme.io,resources=applications/finalizers,verbs=update
-//+kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
-//+kubebuilder:rbac:groups="",resources=services;serviceaccounts,verbs=get;list;watch;create;update;patch;delete
-//+kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=apps,resources=controllerrevisions,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=endpoints,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -113,6 +247,20 @@ func (r *ApplicationReconciler) updateStatus(
 // For more details, check Reconcile and its Result here:
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.14.1/pkg/reconcile
 func (r *ApplicationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	ctx, span := tracer.Start(ctx, "Reconcile", trace.WithAttributes(applicationAttributes(req.NamespacedName)...))
+	result, err := r.reconcile(ctx, req)
+	result, returned := resyncPermanent(result, err, r.ResyncPeriod)
+	if returned == nil && err != nil {
+		log.FromContext(ctx).Error(err, "the reconciliation failed permanently, checking back on it at the resync period", "after", result.RequeueAfter)
+	}
+	span.SetAttributes(attribute.String("acme.reconcile.requeue_after", result.RequeueAfter.String()))
+	endSpan(span, err)
+
+	return result, returned
+}
+
+// reconcile carries out a single reconciliation of the CR, within the span of the reconciliation
+func (r *ApplicationReconciler) reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	reconcileLogger := log.FromContext(ctx)
 
 	reconcileLogger.Info("begin reconciliation")
@@ -125,124 +273,431 @@ func (r *ApplicationReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		if errors.IsNotFound(err) {
 			// The object was deleted or no longer exists, do not infinitely loop
 			// attempting to load the resource after deletion
+			r.forgetMetrics(req.NamespacedName)
 			return ctrl.Result{}, nil
 		}
 		reconcileLogger.Error(err, "cannot get CR from namespace, requeueing attempt and trying again")
-		return ctrl.Result{RequeueAfter: time.Second * 5}, err
+		return requeue(err)
+	}
+
+	// A deleted CR only has its deletion policy carried out, any other change to
+	// the cluster state would be undone by the garbage collector moments later.
+	if !cr.GetDeletionTimestamp().IsZero() {
+		reconcileLogger.Info("the CR is being deleted, carrying out its deletion policy")
+		requeueAfter, err := r.finalize(ctx, reconcileLogger, cr)
+		if err != nil {
+			reconcileLogger.Error(err, "unable to carry out the deletion policy of the CR")
+			return requeue(err)
+		}
+		return ctrl.Result{RequeueAfter: requeueAfter}, nil
+	}
+
+	// The finalizer holds the deletion of the CR until its deletion policy has
+	// been carried out, instead of leaving everything to the garbage collector.
+	if controllerutil.AddFinalizer(cr, FinalizerName) {
+		if err := r.Client.Update(ctx, cr); err != nil {
+			reconcileLogger.Error(err, "unable to add the finalizer to the CR")
+			return requeue(err)
+		}
+	}
+
+	// A rollback only rewrites the spec of the CR, the change to the spec
+	// is what triggers the reconciliation of the rolled back cluster state.
+	if cr.Spec.RollbackTo != nil {
+		reconcileLogger.Info("rolling back to a previous revision", "revision", cr.Spec.RollbackTo.Revision)
+		if err := r.rollback(ctx, cr); err != nil {
+			reconcileLogger.Error(err, "unable to roll back to the requested revision")
+			if err := r.updateStatus(reconcileLogger, ctx, req, false, err); err != nil {
+				return requeue(err)
+			}
+			return requeue(err)
+		}
+		return ctrl.Result{}, nil
+	}
+
+	// Every spec that is reconciled is recorded in the revision history, so
+	// that the cluster state can be rolled back to it later on.
+	revision, err := r.recordRevision(ctx, cr)
+	if err != nil {
+		reconcileLogger.Error(err, "unable to record the current revision of the CR")
+		if err := r.updateStatus(reconcileLogger, ctx, req, false, err); err != nil {
+			return requeue(err)
+		}
+		return requeue(err)
+	}
+
+	// The manifests are generated from the CR itself, with the image rewritten to
+	// the registry mirror it is pulled through, and pinned to the digest it resolves
+	// to when the generation of the CR was first reconciled.  The digest is resolved
+	// against the mirror as the source registry may not be reachable from the cluster.
+	// An image the policy denies is never resolved, so that its registry is not sent
+	// the pull credentials, and is left for the image policy check to report.
+	config := r.Config.Get()
+	app := mirror(withDefaults(cr, config.Defaults), config.Mirrors)
+	imageStatus := withImage("", "")
+	if cr.PinImageDigest() && config.ImagePolicy.Validate(config.Mirrors.Restore(app.Image())) == nil {
+		pinned, digest, err := r.pinImage(ctx, cr, app)
+		if err != nil {
+			reconcileLogger.Error(err, "unable to pin the image to a digest")
+			if err := r.updateStatus(reconcileLogger, ctx, req, false, err); err != nil {
+				return requeue(err)
+			}
+			return requeue(err)
+		}
+		reconcileLogger.Info("pinned the image to a digest", "image", pinned)
+		app = &imageOverride{Application: app, image: pinned}
+		imageStatus = withImage(pinned, digest)
+	}
+
+	// Inside its hibernation window the CR is scaled down to zero replicas, and
+	// the reconciliation is requeued for the exact time the window next opens or
+	// closes, instead of waiting for an unrelated event to come along.
+	app, hibernating, nextTransition, err := hibernate(app, cr.Spec.Hibernation, time.Now())
+	if err != nil {
+		// An invalid window cannot be fixed by retrying, only by changing the CR
+		reconcileLogger.Error(err, "unable to evaluate the hibernation window")
+		if err := r.updateStatus(reconcileLogger, ctx, req, false, err); err != nil {
+			return requeue(err)
+		}
+		return requeue(permanent(err))
+	}
+	hibernationStatus := withHibernation(hibernating, nextTransition)
+	result := ctrl.Result{}
+	if !nextTransition.IsZero() {
+		reconcileLogger.Info("evaluated the hibernation window", "hibernating", hibernating, "next", nextTransition)
+		result.RequeueAfter = time.Until(nextTransition)
+	}
+
+	// Fields that other actors legitimately change, such as injected sidecars,
+	// are left out when looking for drift, so that they are not fought over.
+	ignoreRules, err := driftIgnoreRules(cr)
+	if err != nil {
+		// An invalid rule cannot be fixed by retrying, only by changing the CR
+		reconcileLogger.Error(err, "unable to parse the drift ignore rules")
+		if err := r.updateStatus(reconcileLogger, ctx, req, false, err); err != nil {
+			return requeue(err)
+		}
+		return requeue(permanent(err))
 	}
 
 	// Define a collection of information required to reconcile cluster state
-	toReconcile := []ReconcileWrapper{
-		{
-			Driftor:      acmegdrift.Deployment,
-			Manifest:     acmegenerators.DefaultDeploymentGenerator.Object(cr),
-			ObjectLoader: &appsv1.Deployment{},
-		},
-		{
-			Driftor:      acmegdrift.Service,
-			Manifest:     acmegenerators.DefaultServiceGenerator.Object(cr),
-			ObjectLoader: &corev1.Service{},
-		},
-		{
-			Driftor:      acmegdrift.ServiceAccount,
-			Manifest:     acmegenerators.DefaultServiceAccountGenerator.Object(cr),
-			ObjectLoader: &corev1.ServiceAccount{},
-		},
-		{
-			Driftor:      acmegdrift.Ingress,
-			Manifest:     acmegenerators.DefaultIngressGenerator.Object(cr),
-			ObjectLoader: &networkingv1.Ingress{},
-		},
-	}
-
-	if err := r.updateStatus(reconcileLogger, ctx, req, true, nil); err != nil {
-		return ctrl.Result{RequeueAfter: time.Second * 5}, err
+	toReconcile := manifests(r.generators(), app)
+
+	// The overrides of the CR patch fields the API has no setting for into the
+	// generated manifests, which are reconciled and compared for drift as patched.
+	toReconcile, err = overriding(toReconcile, cr)
+	if err != nil {
+		// An override that does not apply cannot be fixed by retrying, only by changing the CR
+		reconcileLogger.Error(err, "unable to apply the overrides")
+		if err := r.updateStatus(reconcileLogger, ctx, req, false, err); err != nil {
+			return requeue(err)
+		}
+		return requeue(permanent(err))
 	}
 
+	// The extra resources embedded in the CR are reconciled along with the
+	// generated manifests, for as long as the operator allows their kind.
+	toReconcile, err = withExtraResources(toReconcile, cr, &config.ExtraResources)
+	if err != nil {
+		// An extra resource that is not allowed cannot be fixed by retrying, only by changing the CR
+		reconcileLogger.Error(err, "unable to add the extra resources")
+		if err := r.updateStatus(reconcileLogger, ctx, req, false, err); err != nil {
+			return requeue(err)
+		}
+		return requeue(permanent(err))
+	}
+	toReconcile = ignoring(toReconcile, append(append(acmegdrift.IgnoreRules{}, config.IgnoreRules()...), ignoreRules...))
+
+	// A suspended CR leaves the cluster state as is, so that the downstream objects
+	// can be edited by hand, while the drift from the CR is still reported.  The
+	// owned objects are watched, so every hand edit refreshes the report.
+	if cr.Suspend() {
+		drifted, driftRecords, err := r.detectDrift(ctx, cr, toReconcile)
+		if err != nil {
+			reconcileLogger.Error(err, "unable to detect drift while reconciliation is suspended")
+			if err := r.updateStatus(reconcileLogger, ctx, req, false, err); err != nil {
+				return requeue(err)
+			}
+			return requeue(err)
+		}
+		reconcileLogger.Info("reconciliation is suspended, leaving the cluster state as is", "drifted", drifted)
+		if err := r.updateStatus(reconcileLogger, ctx, req, false, nil, withObservedGeneration(cr.GetGeneration()), withRevision(revision), imageStatus, hibernationStatus, withDrift(driftRecords), withCondition(suspendedCondition(cr, drifted))); err != nil {
+			return requeue(err)
+		}
+		return requeueWithin(result, r.ResyncPeriod), nil
+	}
+
+	// Nothing is applied to the cluster for an image that breaks the operator image
+	// policy, and there is no point in retrying until the CR has been changed.
+	policyCondition := metav1.Condition{
+		Type:               acmeiov1beta1.ConditionImagePolicyViolation,
+		Status:             metav1.ConditionFalse,
+		Reason:             "ImagePolicySatisfied",
+		Message:            "the image satisfies the image policy",
+		ObservedGeneration: cr.GetGeneration(),
+	}
+	if err := checkImagePolicy(&config.ImagePolicy, config.Mirrors, cr, toReconcile); err != nil {
+		reconcileLogger.Error(err, "the image does not satisfy the image policy")
+
+		policyCondition.Status = metav1.ConditionTrue
+		policyCondition.Reason = "ImagePolicyViolation"
+		policyCondition.Message = err.Error()
+		if err := r.updateStatus(reconcileLogger, ctx, req, false, err, withObservedGeneration(cr.GetGeneration()), withRevision(revision), imageStatus, hibernationStatus, withCondition(policyCondition)); err != nil {
+			return requeue(err)
+		}
+		return requeue(permanent(err))
+	}
+
+	if err := r.updateStatus(reconcileLogger, ctx, req, true, nil, withObservedGeneration(cr.GetGeneration()), withRevision(revision), imageStatus, hibernationStatus, withCondition(policyCondition), withCondition(suspendedCondition(cr, nil))); err != nil {
+		return requeue(err)
+	}
+
+	// Every downstream object is reconciled on every pass, so that an object that
+	// is already in sync, or that fails to reconcile, never keeps the objects after
+	// it from being created or corrected.  Failures are reported per object.
+	children := []acmeiov1beta1.ApplicationChildStatus{}
+	conflicts := []string{}
+	driftRecords := []acmeiov1beta1.ApplicationDriftRecord{}
+	errs := []error{}
+	deploymentName := ""
 	for _, reconcilers := range toReconcile {
-		// Set the namespace for the generated manifest to
-		// the namespace for the reconciling CR
-		reconcilers.Manifest.SetNamespace(cr.Namespace)
-
-		// If the controller reference is not set, then things like
-		// cascading object garbage collection, state enforcement, and
-		// ownership are not propogated correctly.
-		ctrl.SetControllerReference(cr, reconcilers.Manifest, r.Scheme)
-		objGVK := gvk(reconcilers.Manifest)
-		reconcileLogger.Info(
-			"attempting to reconcile a manifest to correct cluster state for given CR in context",
-			"group",
-			objGVK.Group,
-			"kind",
-			objGVK.Kind,
-			"version",
-			objGVK.Version,
+		started := time.Now()
+		childCtx, span := tracer.Start(ctx, "ReconcileChild", trace.WithAttributes(objectAttributes(gvk(reconcilers.Manifest), reconcilers.Manifest.GetName())...))
+		child, drift, err := r.reconcileChild(reconcileLogger, childCtx, cr, reconcilers)
+		span.SetAttributes(
+			attribute.String("acme.child.outcome", child.Reason),
+			attribute.Bool("acme.child.synced", child.Synced),
+			attribute.Int("acme.child.drifted_fields", len(drift.Differences)),
 		)
+		endSpan(span, err)
+		observeChild(cr, child, time.Since(started))
+		if drift.Drifted() {
+			observeDrift(cr.GetNamespace(), cr.GetName(), child.Kind)
+			driftRecords = append(driftRecords, driftRecord(child.Kind, child.Name, drift, time.Now()))
+		}
+		if child.Kind == "Deployment" {
+			deploymentName = child.Name
+			if child.Reason == childReasonCreated || child.Reason == childReasonUpdated || child.Reason == childReasonAdopted {
+				r.startRollout(req.NamespacedName, started)
+			}
+		}
+		if err != nil {
+			reconcileLogger.Error(err, "unable to reconcile a downstream object", "kind", child.Kind, "name", child.Name)
+			errs = append(errs, fmt.Errorf("%s %s: %w", child.Kind, child.Name, err))
+		}
+		r.recordChild(cr, child)
+		if child.Reason == childReasonOwnershipConflict {
+			conflicts = append(conflicts, fmt.Sprintf("%s %s", child.Kind, child.Name))
+		}
+		children = append(children, child)
+	}
 
-		// Attempt to create the object, assume it does no exist, and
-		// handle edge scenarios from there.
-		if err := r.Client.Create(ctx, reconcilers.Manifest); err != nil {
-			if errors.IsAlreadyExists(err) {
-				// In this scenario the object already exists on the cluster,
-				// so the reconciler must check to see if the object has
-				// drifted from the defined cluster state, or if there is no
-				// detectable drift.
-				//
-				// Without checking the cluster state drift, the reconciler
-				// will be stuck in an infinite loop as it will perform no-op
-				// udpdates to the existing cluster objects.
-				found := reconcilers.ObjectLoader
-				found.SetNamespace(reconcilers.Manifest.GetNamespace())
-				found.SetName(reconcilers.Manifest.GetName())
-				if err := r.Client.Get(ctx, client.ObjectKeyFromObject(found), found); err != nil {
-					// We cannot determine if drift exists or not if we cannot
-					// grab the current object state from the cluster.
-					if err := r.updateStatus(reconcileLogger, ctx, req, false, err); err != nil {
-						return ctrl.Result{RequeueAfter: time.Second * 5}, err
-					}
-					return ctrl.Result{RequeueAfter: time.Second * 5}, err
-				}
-				if !reconcilers.Driftor(reconcilers.Manifest, found) {
-					// No drift detected is an indicator that
-					// no reconciliation is required.
-					return ctrl.Result{}, nil
-				}
-
-				reconcileLogger.Info("found a conflicting object state on the cluster, overriding definition to match expected cluster state")
-				if err := r.Client.Update(ctx, reconcilers.Manifest); err != nil {
-					// When this happens the cluster is in a dirty state where
-					// there is drift that cannot be recovered from, meaning the
-					// current cluster state is not valid to the CR definition
-					reconcileLogger.Error(err, "unable to update object to restore expected cluster state")
-					if err := r.updateStatus(reconcileLogger, ctx, req, false, err); err != nil {
-						return ctrl.Result{RequeueAfter: time.Second * 5}, err
-					}
-					return ctrl.Result{RequeueAfter: time.Second * 5}, err
-				}
-			} else {
-				reconcileLogger.Error(err, "unable to create require downstream manifest to support application deployment")
-				if err := r.updateStatus(reconcileLogger, ctx, req, false, err); err != nil {
-					return ctrl.Result{RequeueAfter: time.Second * 5}, err
-				}
-				return ctrl.Result{RequeueAfter: time.Second * 5}, err
+	// Extra resources removed from the CR are deleted, rather than left behind
+	// until the CR itself is deleted.
+	pruned, err := r.pruneExtraResources(ctx, cr, toReconcile, &config.ExtraResources)
+	if err != nil {
+		reconcileLogger.Error(err, "unable to delete the extra resources removed from the CR")
+		errs = append(errs, err)
+	}
+	children = append(children, pruned...)
+
+	// The rollout is measured on the cluster state, so a failure to measure it is
+	// no reason to fail the reconciliation, which would only delay the rollout.
+	if err := r.observeRollout(ctx, req.NamespacedName, deploymentName); err != nil {
+		reconcileLogger.Error(err, "unable to check on the rollout of the Deployment")
+	}
+
+	reconcileErr := utilerrors.NewAggregate(errs)
+	if err := r.updateStatus(reconcileLogger, ctx, req, false, reconcileErr, withChildren(children), withDrift(driftRecords), withCondition(ownershipCondition(cr, conflicts)), withCondition(driftCondition(cr, children))); err != nil {
+		return requeue(err)
+	}
+	if reconcileErr != nil {
+		return requeue(reconcileErr)
+	}
+
+	// Objects the CR does not control are not watched, so marking them for
+	// adoption is only noticed by checking back on them every so often.
+	if len(conflicts) > 0 {
+		result = requeueWithin(result, time.Second*30)
+	}
+
+	return requeueWithin(result, r.ResyncPeriod), nil
+}
+
+// reconcileChild brings a single downstream object to the state defined by the CR, and reports the outcome
+// as the status of that object, along with the drift that was corrected
+func (r *ApplicationReconciler) reconcileChild(
+	reconcileLogger logr.Logger,
+	ctx context.Context,
+	cr *acmeiov1beta1.Application,
+	reconcilers ReconcileWrapper,
+) (acmeiov1beta1.ApplicationChildStatus, acmegdrift.Report, error) {
+	drift := acmegdrift.Report{}
+
+	// Set the namespace for the generated manifest to
+	// the namespace for the reconciling CR
+	reconcilers.Manifest.SetNamespace(cr.Namespace)
+
+	objGVK := gvk(reconcilers.Manifest)
+	child := acmeiov1beta1.ApplicationChildStatus{
+		APIVersion: objGVK.GroupVersion().String(),
+		Kind:       objGVK.Kind,
+		Name:       reconcilers.Manifest.GetName(),
+	}
+
+	// If the controller reference is not set, then things like
+	// cascading object garbage collection, state enforcement, and
+	// ownership are not propogated correctly.
+	if err := ctrl.SetControllerReference(cr, reconcilers.Manifest, r.Scheme); err != nil {
+		return failedChild(child, err), drift, err
+	}
+
+	// The hash of the manifest is recorded on the object, so that a later
+	// reconciliation can tell if the object was applied from another manifest.
+	if err := acmegdrift.Stamp(reconcilers.Manifest); err != nil {
+		return failedChild(child, err), drift, err
+	}
+	reconcileLogger.Info(
+		"attempting to reconcile a manifest to correct cluster state for given CR in context",
+		"group",
+		objGVK.Group,
+		"kind",
+		objGVK.Kind,
+		"version",
+		objGVK.Version,
+	)
+
+	// Load the current state of the object, when it exists, so that the
+	// reconciler can check that the object is its own to manage, and if
+	// the object has drifted from the defined cluster state.
+	//
+	// Without checking the cluster state drift, the reconciler
+	// will be stuck in an infinite loop as it will perform no-op
+	// udpdates to the existing cluster objects.
+	child.Reason = childReasonCreated
+	found := reconcilers.ObjectLoader
+	found.SetNamespace(reconcilers.Manifest.GetNamespace())
+	found.SetName(reconcilers.Manifest.GetName())
+	getCtx, getSpan := tracer.Start(ctx, "Get")
+	err := r.getChild(getCtx, found)
+	getSpan.SetAttributes(attribute.Bool("k8s.object.found", err == nil))
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			// We cannot determine if drift exists or not if we cannot
+			// grab the current object state from the cluster.
+			endSpan(getSpan, err)
+			return failedChild(child, err), drift, err
+		}
+		getSpan.End()
+		reconcileLogger.Info("the object does not exist yet, creating it to support application deployment")
+	} else {
+		getSpan.End()
+
+		// Objects the CR does not control are left as they are, as they
+		// may well belong to something else entirely, unless they have
+		// explicitly been marked for adoption.
+		if !mayManage(cr, found) {
+			reconcileLogger.Info("found an object the CR does not control, leaving it as is", "kind", child.Kind, "name", child.Name)
+			r.Recorder.Event(cr, corev1.EventTypeWarning, "OwnershipConflict", fmt.Sprintf("%s %s exists and is not controlled by the Application", child.Kind, child.Name))
+			child.Reason = childReasonOwnershipConflict
+			child.Message = "the object exists and is not controlled by the Application"
+			return child, drift, nil
+		}
+		adopting := !metav1.IsControlledBy(found, cr)
+		if adopting {
+			reconcileLogger.Info("adopting an existing object marked for adoption")
+			child.Reason = childReasonAdopted
+		} else {
+			// The object is left as it is once it exists, whatever state it
+			// is in, for as long as the policy is Ignore, but a change to the
+			// Application is applied to it under every policy.
+			policy := cr.DriftPolicyFor(child.Kind)
+			detect := reconcilers.Driftor
+			if policy == acmeiov1beta1.DriftPolicyIgnore {
+				detect = acmegdrift.Changes
+			}
+
+			_, driftSpan := tracer.Start(ctx, "DetectDrift", trace.WithAttributes(attribute.String("acme.drift.policy", string(policy))))
+			report, err := detect(reconcilers.Manifest, found)
+			driftSpan.SetAttributes(
+				attribute.Bool("acme.drift.changed", report.Changed),
+				attribute.Int("acme.drift.drifted_fields", len(report.Differences)),
+			)
+			endSpan(driftSpan, err)
+			if err != nil {
+				// An object that cannot be compared is reported rather than
+				// overwritten, as there is no telling what state it is in.
+				reconcileLogger.Error(err, "unable to compare the object to its manifest")
+				return failedChild(child, err), drift, fmt.Errorf("unable to detect drift on %s %s: %w", child.Kind, child.Name, err)
+			}
+			drift = report
+
+			switch {
+			case drift.Changed:
+				reconcileLogger.Info("the Application changed since the object was applied, applying the new manifest")
+				child.Reason = childReasonUpdated
+			case policy == acmeiov1beta1.DriftPolicyIgnore:
+				child.Synced = true
+				child.Reason = childReasonDriftIgnored
+				child.Message = "drift is ignored by the drift policy"
+				return child, drift, nil
+			case !drift.Drifted():
+				// No drift detected is an indicator that
+				// no reconciliation is required.
+				child.Synced = true
+				child.Reason = childReasonInSync
+				return child, drift, nil
+			case policy == acmeiov1beta1.DriftPolicyReport:
+				reconcileLogger.Info("found a conflicting object state on the cluster, leaving it as is as the drift policy is Report", "drift", drift.String())
+				r.recordDrift(cr, child.Kind, child.Name, "not correcting it as the drift policy is Report", drift)
+				child.Reason = childReasonDrifted
+				child.Message = drift.String()
+				return child, drift, nil
+			default:
+				reconcileLogger.Info("found a conflicting object state on the cluster, overriding definition to match expected cluster state", "drift", drift.String())
+				r.recordDrift(cr, child.Kind, child.Name, "correcting it", drift)
+				child.Reason = childReasonUpdated
 			}
 		}
 	}
 
-	if err := r.updateStatus(reconcileLogger, ctx, req, false, nil); err != nil {
-		return ctrl.Result{RequeueAfter: time.Second * 5}, err
+	// The object is applied server side, so that the controller only owns the
+	// fields it generates, and the fields set by other actors, such as added
+	// annotations or injected sidecars, are left as they are.
+	step := "Update"
+	if child.Reason == childReasonCreated {
+		step = "Create"
+	}
+	applyCtx, applySpan := tracer.Start(ctx, step)
+	err = r.apply(applyCtx, reconcilers.Manifest)
+	endSpan(applySpan, err)
+	if err != nil {
+		// When this happens the cluster is in a dirty state where
+		// there is drift that cannot be recovered from, meaning the
+		// current cluster state is not valid to the CR definition
+		return failedChild(child, err), drift, err
 	}
 
-	return ctrl.Result{}, nil
+	child.Synced = true
+	return child, drift, nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *ApplicationReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&acmeiov1beta1.Application{}).
-		Owns(&appsv1.Deployment{}).
-		Owns(&corev1.Service{}).
-		Owns(&corev1.ServiceAccount{}).
-		WithEventFilter(predicate.GenerationChangedPredicate{}).
+	// Status updates do not change the generation of the CR, which keeps the
+	// controller from reconciling its own status updates over and over.  The
+	// predicate is not set on the owned objects, as core kinds such as Service
+	// and ServiceAccount never change their generation on edits.
+	blder := ctrl.NewControllerManagedBy(mgr).
+		For(&acmeiov1beta1.Application{}, builder.WithPredicates(predicate.GenerationChangedPredicate{}))
+	for _, registration := range r.generators().Registrations() {
+		blder = blder.Owns(registration.New())
+	}
+
+	return blder.
+		WithOptions(controller.Options{
+			MaxConcurrentReconciles: r.MaxConcurrentReconciles,
+			RateLimiter:             r.Backoff.RateLimiter(),
+		}).
 		Complete(r)
 }
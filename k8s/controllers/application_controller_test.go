@@ -0,0 +1,100 @@
+/*
+Copyright 2023 Nathan Brophy.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	acmeapi "github.com/nathanbrophy/portfolio-demo/k8s/api"
+	acmegdrift "github.com/nathanbrophy/portfolio-demo/k8s/driftDetection"
+	acmegenerators "github.com/nathanbrophy/portfolio-demo/k8s/generators"
+	acmetest "github.com/nathanbrophy/portfolio-demo/k8s/utils/test"
+)
+
+func TestRequeueWithin(t *testing.T) {
+	tests := []struct {
+		name   string
+		result ctrl.Result
+		period time.Duration
+		want   time.Duration
+	}{
+		{
+			name:   "no requeue",
+			result: ctrl.Result{},
+			period: time.Minute,
+			want:   time.Minute,
+		},
+		{
+			name:   "earlier requeue is kept",
+			result: ctrl.Result{RequeueAfter: time.Second * 30},
+			period: time.Minute,
+			want:   time.Second * 30,
+		},
+		{
+			name:   "later requeue is brought forward",
+			result: ctrl.Result{RequeueAfter: time.Hour},
+			period: time.Minute,
+			want:   time.Minute,
+		},
+		{
+			name:   "zero period",
+			result: ctrl.Result{RequeueAfter: time.Hour},
+			period: 0,
+			want:   time.Hour,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := requeueWithin(tt.result, tt.period); got.RequeueAfter != tt.want {
+				t.Errorf("requeueWithin() = %v, want %v", got.RequeueAfter, tt.want)
+			}
+		})
+	}
+}
+
+func TestManifests(t *testing.T) {
+	registry := &acmegenerators.Registry{}
+	registry.Register(acmegenerators.Registration{
+		GVK:       schema.GroupVersionKind{Version: "v1", Kind: "Service"},
+		Generator: acmegenerators.DefaultServiceGenerator,
+		Driftor:   acmegdrift.Generic,
+		Type:      &corev1.Service{},
+	})
+	registry.Register(acmegenerators.Registration{
+		GVK:       schema.GroupVersionKind{Version: "v1", Kind: "ServiceAccount"},
+		Generator: acmegenerators.DefaultServiceAccountGenerator,
+		Applies:   func(in acmeapi.Application) bool { return in.ServiceAccount() == nil },
+		Driftor:   acmegdrift.Generic,
+		Type:      &corev1.ServiceAccount{},
+	})
+
+	toReconcile := manifests(registry, acmetest.GenerateCRWithDefaults())
+	if len(toReconcile) != 1 {
+		t.Fatalf("manifests() = %d manifests, want only the registered kinds that apply", len(toReconcile))
+	}
+	if _, ok := toReconcile[0].Manifest.(*corev1.Service); !ok {
+		t.Errorf("manifests() = %T, want a Service", toReconcile[0].Manifest)
+	}
+	if _, ok := toReconcile[0].ObjectLoader.(*corev1.Service); !ok {
+		t.Errorf("manifests() loads into a %T, want a Service", toReconcile[0].ObjectLoader)
+	}
+}
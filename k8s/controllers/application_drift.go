@@ -0,0 +1,137 @@
+/*
+Copyright 2023 Nathan Brophy.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	acmeiov1beta1 "github.com/nathanbrophy/portfolio-demo/k8s/api/v1beta1"
+	acmegdrift "github.com/nathanbrophy/portfolio-demo/k8s/driftDetection"
+)
+
+const (
+	// maxDriftRecords bounds the number of drift records kept in the status of the CR
+	maxDriftRecords int = 10
+
+	// maxDriftDifferences bounds the number of differences kept in a single drift record
+	maxDriftDifferences int = 10
+
+	// maxEventMessageLength is the longest message the API server accepts for an Event
+	maxEventMessageLength int = 1024
+)
+
+// driftRecord converts the drift report of a downstream object to its record in the status of the CR
+func driftRecord(kind, name string, report acmegdrift.Report, now time.Time) acmeiov1beta1.ApplicationDriftRecord {
+	record := acmeiov1beta1.ApplicationDriftRecord{
+		Time:        metav1.Time{Time: now},
+		Kind:        kind,
+		Name:        name,
+		Differences: []acmeiov1beta1.ApplicationDriftDifference{},
+	}
+	for _, difference := range report.Differences {
+		if len(record.Differences) == maxDriftDifferences {
+			break
+		}
+		record.Differences = append(record.Differences, acmeiov1beta1.ApplicationDriftDifference{
+			Path:     difference.Path,
+			Expected: difference.Expected,
+			Actual:   difference.Actual,
+		})
+	}
+
+	return record
+}
+
+// withDrift adds the drift records to the status of the CR, newest first, keeping at most maxDriftRecords.  A record
+// that repeats the newest record of the same object is dropped, so that drift that is reported on every pass, but
+// never corrected, is only recorded once.
+func withDrift(records []acmeiov1beta1.ApplicationDriftRecord) statusMutator {
+	return func(status *acmeiov1beta1.ApplicationStatus) {
+		for _, record := range records {
+			if repeated(status.RecentDrift, record) {
+				continue
+			}
+			status.RecentDrift = append([]acmeiov1beta1.ApplicationDriftRecord{record}, status.RecentDrift...)
+		}
+		if len(status.RecentDrift) > maxDriftRecords {
+			status.RecentDrift = status.RecentDrift[:maxDriftRecords]
+		}
+	}
+}
+
+// repeated reports if the newest record of the same object in the history has the same differences as the record
+func repeated(history []acmeiov1beta1.ApplicationDriftRecord, record acmeiov1beta1.ApplicationDriftRecord) bool {
+	for _, previous := range history {
+		if previous.Kind == record.Kind && previous.Name == record.Name {
+			return reflect.DeepEqual(previous.Differences, record.Differences)
+		}
+	}
+
+	return false
+}
+
+// driftIgnoreRules builds the rules for the fields of the downstream objects that the CR leaves out when looking for drift
+func driftIgnoreRules(cr *acmeiov1beta1.Application) (acmegdrift.IgnoreRules, error) {
+	rules := acmegdrift.IgnoreRules{}
+	for _, ignore := range cr.Spec.DriftIgnore {
+		for _, path := range ignore.Paths {
+			rule, err := acmegdrift.ParseIgnoreRule(ignore.Kind, path)
+			if err != nil {
+				return nil, err
+			}
+			rules = append(rules, rule)
+		}
+	}
+
+	return rules, nil
+}
+
+// driftCondition builds the DriftDetected condition for the CR from the objects whose drift was left uncorrected
+func driftCondition(cr *acmeiov1beta1.Application, children []acmeiov1beta1.ApplicationChildStatus) metav1.Condition {
+	condition := metav1.Condition{
+		Type:               acmeiov1beta1.ConditionDriftDetected,
+		Status:             metav1.ConditionFalse,
+		Reason:             "NoUncorrectedDrift",
+		Message:            "no downstream object has drift that is left uncorrected",
+		ObservedGeneration: cr.GetGeneration(),
+	}
+
+	drifted := []string{}
+	for _, child := range children {
+		if child.Reason == childReasonDrifted {
+			drifted = append(drifted, fmt.Sprintf("%s %s (%s)", child.Kind, child.Name, child.Message))
+		}
+	}
+	if len(drifted) > 0 {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = "DriftDetected"
+		condition.Message = "drift is left uncorrected by the drift policy: " + strings.Join(drifted, ", ")
+	}
+
+	return condition
+}
+
+// recordDrift emits the drift report of a downstream object as an Event on the CR
+func (r *ApplicationReconciler) recordDrift(cr *acmeiov1beta1.Application, kind, name, action string, report acmegdrift.Report) {
+	r.Recorder.Event(cr, corev1.EventTypeWarning, "DriftDetected", truncateEvent(fmt.Sprintf("%s %s has drifted, %s: %s", kind, name, action, report.String())))
+}
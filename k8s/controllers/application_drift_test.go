@@ -0,0 +1,163 @@
+/*
+Copyright 2023 Nathan Brophy.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	acmeiov1beta1 "github.com/nathanbrophy/portfolio-demo/k8s/api/v1beta1"
+	acmegdrift "github.com/nathanbrophy/portfolio-demo/k8s/driftDetection"
+)
+
+func TestWithDrift(t *testing.T) {
+	replicas := func(actual string) acmegdrift.Report {
+		return acmegdrift.Report{Differences: []acmegdrift.Difference{{Path: "/spec/replicas", Expected: "2", Actual: actual}}}
+	}
+	now := time.Now()
+
+	tests := []struct {
+		name     string
+		history  []acmeiov1beta1.ApplicationDriftRecord
+		records  []acmeiov1beta1.ApplicationDriftRecord
+		wantLen  int
+		wantHead string
+	}{
+		{
+			name:     "newest first",
+			history:  []acmeiov1beta1.ApplicationDriftRecord{driftRecord("Deployment", "app", replicas("3"), now)},
+			records:  []acmeiov1beta1.ApplicationDriftRecord{driftRecord("Service", "app", replicas("3"), now)},
+			wantLen:  2,
+			wantHead: "Service",
+		},
+		{
+			name:     "repeated drift is recorded once",
+			history:  []acmeiov1beta1.ApplicationDriftRecord{driftRecord("Deployment", "app", replicas("3"), now)},
+			records:  []acmeiov1beta1.ApplicationDriftRecord{driftRecord("Deployment", "app", replicas("3"), now.Add(time.Minute))},
+			wantLen:  1,
+			wantHead: "Deployment",
+		},
+		{
+			name:     "changed drift is recorded again",
+			history:  []acmeiov1beta1.ApplicationDriftRecord{driftRecord("Deployment", "app", replicas("3"), now)},
+			records:  []acmeiov1beta1.ApplicationDriftRecord{driftRecord("Deployment", "app", replicas("4"), now.Add(time.Minute))},
+			wantLen:  2,
+			wantHead: "Deployment",
+		},
+		{
+			name: "history is bounded",
+			history: func() []acmeiov1beta1.ApplicationDriftRecord {
+				history := []acmeiov1beta1.ApplicationDriftRecord{}
+				for i := 0; i < maxDriftRecords; i++ {
+					history = append(history, driftRecord("Deployment", fmt.Sprintf("app-%d", i), replicas("3"), now))
+				}
+				return history
+			}(),
+			records:  []acmeiov1beta1.ApplicationDriftRecord{driftRecord("Ingress", "app", replicas("3"), now)},
+			wantLen:  maxDriftRecords,
+			wantHead: "Ingress",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := &acmeiov1beta1.ApplicationStatus{RecentDrift: tt.history}
+			withDrift(tt.records)(status)
+			if len(status.RecentDrift) != tt.wantLen {
+				t.Errorf("withDrift() records = %v, want %v", len(status.RecentDrift), tt.wantLen)
+			}
+			if status.RecentDrift[0].Kind != tt.wantHead {
+				t.Errorf("withDrift() newest record = %v, want %v", status.RecentDrift[0].Kind, tt.wantHead)
+			}
+		})
+	}
+}
+
+func TestDriftCondition(t *testing.T) {
+	tests := []struct {
+		name       string
+		children   []acmeiov1beta1.ApplicationChildStatus
+		wantStatus metav1.ConditionStatus
+	}{
+		{
+			name: "corrected and ignored drift",
+			children: []acmeiov1beta1.ApplicationChildStatus{
+				{Kind: "Deployment", Name: "acme-application", Synced: true, Reason: childReasonUpdated},
+				{Kind: "Service", Name: "acme-application", Synced: true, Reason: childReasonDriftIgnored},
+			},
+			wantStatus: metav1.ConditionFalse,
+		},
+		{
+			name: "reported drift",
+			children: []acmeiov1beta1.ApplicationChildStatus{
+				{Kind: "Deployment", Name: "acme-application", Synced: true, Reason: childReasonInSync},
+				{Kind: "Service", Name: "acme-application", Reason: childReasonDrifted, Message: "/spec/type"},
+			},
+			wantStatus: metav1.ConditionTrue,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := driftCondition(&acmeiov1beta1.Application{}, tt.children); got.Status != tt.wantStatus {
+				t.Errorf("driftCondition() status = %v, want %v", got.Status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestDriftIgnoreRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		ignore  []acmeiov1beta1.ApplicationDriftIgnoreRule
+		wantLen int
+		wantErr bool
+	}{
+		{
+			name:    "no rules",
+			wantLen: 0,
+		},
+		{
+			name: "rules",
+			ignore: []acmeiov1beta1.ApplicationDriftIgnoreRule{
+				{Kind: "Deployment", Paths: []string{"spec.template.spec.containers", "/spec/replicas"}},
+				{Paths: []string{"metadata.annotations[sidecar.istio.io/status]"}},
+			},
+			wantLen: 3,
+		},
+		{
+			name: "invalid path",
+			ignore: []acmeiov1beta1.ApplicationDriftIgnoreRule{
+				{Paths: []string{"/spec//replicas"}},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cr := &acmeiov1beta1.Application{Spec: acmeiov1beta1.ApplicationSpec{DriftIgnore: tt.ignore}}
+			got, err := driftIgnoreRules(cr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("driftIgnoreRules() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && len(got) != tt.wantLen {
+				t.Errorf("driftIgnoreRules() = %v, want %v rules", got, tt.wantLen)
+			}
+		})
+	}
+}
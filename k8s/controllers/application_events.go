@@ -0,0 +1,101 @@
+/*
+Copyright 2023 Nathan Brophy.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	acmeiov1beta1 "github.com/nathanbrophy/portfolio-demo/k8s/api/v1beta1"
+)
+
+// recordChild emits an Event on the CR for a downstream object that was changed on the cluster, or failed to
+// reconcile.  Objects left as they are raise no Event, apart from the ones with drift or ownership conflicts, which
+// are recorded where they are found.
+func (r *ApplicationReconciler) recordChild(cr *acmeiov1beta1.Application, child acmeiov1beta1.ApplicationChildStatus) {
+	switch child.Reason {
+	case childReasonCreated:
+		r.Recorder.Eventf(cr, corev1.EventTypeNormal, "Created", "created %s %s", child.Kind, child.Name)
+	case childReasonUpdated:
+		r.Recorder.Eventf(cr, corev1.EventTypeNormal, "Updated", "updated %s %s to the state defined by the Application", child.Kind, child.Name)
+	case childReasonAdopted:
+		r.Recorder.Eventf(cr, corev1.EventTypeNormal, "Adopted", "adopted the existing %s %s", child.Kind, child.Name)
+	case childReasonFailed:
+		r.Recorder.Event(cr, corev1.EventTypeWarning, "Failed", truncateEvent(fmt.Sprintf("unable to reconcile %s %s: %s", child.Kind, child.Name, child.Message)))
+	}
+}
+
+// recordTransitions emits an Event on the CR for every change to its status that tells the story of the
+// reconciliation, such as a failure, a new revision or a condition that changed, rather than for every update
+func (r *ApplicationReconciler) recordTransitions(cr *acmeiov1beta1.Application, previous, current *acmeiov1beta1.ApplicationStatus) {
+	if strings.HasPrefix(current.Reason, "failed") && current.Reason != previous.Reason {
+		r.Recorder.Event(cr, corev1.EventTypeWarning, "ReconcileFailed", truncateEvent(current.Reason))
+	}
+
+	if current.Revision != previous.Revision && current.Revision != 0 {
+		r.Recorder.Eventf(cr, corev1.EventTypeNormal, "RevisionChanged", "reconciling revision %d", current.Revision)
+	}
+	if current.ResolvedImage != previous.ResolvedImage && current.ResolvedImage != "" {
+		r.Recorder.Eventf(cr, corev1.EventTypeNormal, "ImagePinned", "pinned the image to %s", current.ResolvedImage)
+	}
+	if current.Hibernating != previous.Hibernating {
+		if current.Hibernating {
+			r.Recorder.Event(cr, corev1.EventTypeNormal, "Hibernating", "the hibernation window opened, scaling down to zero replicas")
+		} else {
+			r.Recorder.Event(cr, corev1.EventTypeNormal, "WokeUp", "the hibernation window closed, scaling back up")
+		}
+	}
+
+	for _, condition := range current.Conditions {
+		if message, changed := conditionTransition(previous.Conditions, condition); changed {
+			// Every condition is abnormal while true, so only those are warnings
+			eventType := corev1.EventTypeNormal
+			if condition.Status == metav1.ConditionTrue {
+				eventType = corev1.EventTypeWarning
+			}
+			r.Recorder.Event(cr, eventType, condition.Reason, truncateEvent(message))
+		}
+	}
+}
+
+// conditionTransition describes a condition that was raised, cleared or changed its reason or message.  A condition
+// that is reported for the first time as false changes nothing, as it only confirms the normal state.
+func conditionTransition(previous []metav1.Condition, condition metav1.Condition) (string, bool) {
+	found := meta.FindStatusCondition(previous, condition.Type)
+	if found == nil {
+		if condition.Status != metav1.ConditionTrue {
+			return "", false
+		}
+	} else if found.Status == condition.Status && found.Reason == condition.Reason && found.Message == condition.Message {
+		return "", false
+	}
+
+	return fmt.Sprintf("%s is %s: %s", condition.Type, condition.Status, condition.Message), true
+}
+
+// truncateEvent bounds the length of an Event message, so that long reports still fit in an Event
+func truncateEvent(message string) string {
+	if len(message) > maxEventMessageLength {
+		return message[:maxEventMessageLength-3] + "..."
+	}
+
+	return message
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2023 Nathan Brophy.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	acmeiov1beta1 "github.com/nathanbrophy/portfolio-demo/k8s/api/v1beta1"
+)
+
+// recorded drains the Events recorded so far, as their type and reason
+func recorded(recorder *record.FakeRecorder) []string {
+	events := []string{}
+	for {
+		select {
+		case event := <-recorder.Events:
+			fields := strings.Fields(event)
+			events = append(events, fields[0]+" "+fields[1])
+		default:
+			return events
+		}
+	}
+}
+
+func TestRecordTransitions(t *testing.T) {
+	suspended := metav1.Condition{Type: acmeiov1beta1.ConditionSuspended, Status: metav1.ConditionTrue, Reason: "Suspended", Message: "reconciliation is suspended"}
+	resumed := metav1.Condition{Type: acmeiov1beta1.ConditionSuspended, Status: metav1.ConditionFalse, Reason: "Reconciling", Message: "the cluster state is being reconciled"}
+
+	tests := []struct {
+		name     string
+		previous acmeiov1beta1.ApplicationStatus
+		current  acmeiov1beta1.ApplicationStatus
+		want     []string
+	}{
+		{
+			name:     "progress only",
+			previous: acmeiov1beta1.ApplicationStatus{Reason: "reconciling cluster state", Progressing: true},
+			current:  acmeiov1beta1.ApplicationStatus{Reason: "completed"},
+			want:     []string{},
+		},
+		{
+			name:     "failure",
+			previous: acmeiov1beta1.ApplicationStatus{Reason: "completed"},
+			current:  acmeiov1beta1.ApplicationStatus{Reason: "failed to reconcile cluster state due to error: boom"},
+			want:     []string{"Warning ReconcileFailed"},
+		},
+		{
+			name:     "same failure",
+			previous: acmeiov1beta1.ApplicationStatus{Reason: "failed to reconcile cluster state due to error: boom"},
+			current:  acmeiov1beta1.ApplicationStatus{Reason: "failed to reconcile cluster state due to error: boom", Revision: 2},
+			want:     []string{"Normal RevisionChanged"},
+		},
+		{
+			name:     "hibernation",
+			previous: acmeiov1beta1.ApplicationStatus{},
+			current:  acmeiov1beta1.ApplicationStatus{Hibernating: true},
+			want:     []string{"Normal Hibernating"},
+		},
+		{
+			name:     "first condition is normal",
+			previous: acmeiov1beta1.ApplicationStatus{},
+			current:  acmeiov1beta1.ApplicationStatus{Conditions: []metav1.Condition{resumed}},
+			want:     []string{},
+		},
+		{
+			name:     "condition raised",
+			previous: acmeiov1beta1.ApplicationStatus{Conditions: []metav1.Condition{resumed}},
+			current:  acmeiov1beta1.ApplicationStatus{Conditions: []metav1.Condition{suspended}},
+			want:     []string{"Warning Suspended"},
+		},
+		{
+			name:     "condition cleared",
+			previous: acmeiov1beta1.ApplicationStatus{Conditions: []metav1.Condition{suspended}},
+			current:  acmeiov1beta1.ApplicationStatus{Conditions: []metav1.Condition{resumed}},
+			want:     []string{"Normal Reconciling"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(10)
+			r := &ApplicationReconciler{Recorder: recorder}
+
+			r.recordTransitions(&acmeiov1beta1.Application{}, &tt.previous, &tt.current)
+			if got := recorded(recorder); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("recordTransitions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordChild(t *testing.T) {
+	recorder := record.NewFakeRecorder(10)
+	r := &ApplicationReconciler{Recorder: recorder}
+
+	for _, reason := range []string{childReasonCreated, childReasonUpdated, childReasonAdopted, childReasonInSync, childReasonDriftIgnored, childReasonFailed} {
+		r.recordChild(&acmeiov1beta1.Application{}, acmeiov1beta1.ApplicationChildStatus{Kind: "Deployment", Name: "acme-application", Reason: reason})
+	}
+
+	want := []string{"Normal Created", "Normal Updated", "Normal Adopted", "Warning Failed"}
+	if got := recorded(recorder); !reflect.DeepEqual(got, want) {
+		t.Errorf("recordChild() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,164 @@
+/*
+Copyright 2023 Nathan Brophy.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	acmeiov1beta1 "github.com/nathanbrophy/portfolio-demo/k8s/api/v1beta1"
+	acmegdrift "github.com/nathanbrophy/portfolio-demo/k8s/driftDetection"
+	acmegenerators "github.com/nathanbrophy/portfolio-demo/k8s/generators"
+	acmepolicy "github.com/nathanbrophy/portfolio-demo/k8s/policy"
+)
+
+// ExtraResourceLabel is set on the extra resources of an Application, so that an extra resource removed from the
+// spec can be told apart from the generated objects when it is deleted
+const ExtraResourceLabel string = "acme.io/extra-resource"
+
+// withExtraResources adds the extra resources of the CR to the generated manifests.  The operator config may have
+// changed since the CR was admitted, so the kinds are checked against the policy in force, and an extra resource must
+// not share its kind and name with any other object of the CR, which it would otherwise keep overwriting.
+func withExtraResources(toReconcile []ReconcileWrapper, cr *acmeiov1beta1.Application, policy *acmepolicy.ExtraResourcePolicy) ([]ReconcileWrapper, error) {
+	seen := map[string]bool{}
+	for _, reconcilers := range toReconcile {
+		seen[childKey(gvk(reconcilers.Manifest).GroupKind(), reconcilers.Manifest.GetName())] = true
+	}
+
+	for i := range cr.Spec.ExtraResources {
+		obj, err := cr.ExtraResource(i)
+		if err != nil {
+			return nil, fmt.Errorf("extra resource %d: %w", i, err)
+		}
+
+		kind := obj.GroupVersionKind().GroupKind()
+		if err := policy.Validate(kind); err != nil {
+			return nil, fmt.Errorf("extra resource %d: %w", i, err)
+		}
+		if namespace := obj.GetNamespace(); namespace != "" && namespace != cr.GetNamespace() {
+			return nil, fmt.Errorf("extra resource %d: %s %s is in namespace %s, not the namespace of the Application", i, obj.GetKind(), obj.GetName(), namespace)
+		}
+		key := childKey(kind, obj.GetName())
+		if seen[key] {
+			return nil, fmt.Errorf("extra resource %d: %s is already an object of the Application", i, key)
+		}
+		seen[key] = true
+
+		labels := obj.GetLabels()
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		labels[acmegenerators.ManagedByLabel] = acmegenerators.ManagedBy
+		labels[ExtraResourceLabel] = "true"
+		obj.SetLabels(labels)
+
+		loader := &unstructured.Unstructured{}
+		loader.SetGroupVersionKind(obj.GroupVersionKind())
+		toReconcile = append(toReconcile, ReconcileWrapper{
+			Driftor:      acmegdrift.Generic,
+			Manifest:     obj,
+			ObjectLoader: loader,
+		})
+	}
+
+	return toReconcile, nil
+}
+
+// pruneExtraResources deletes the extra resources of the CR that are no longer in its spec.  They are found by their
+// label and controller reference, for every kind the operator allows and every kind the CR still reports in its
+// status, rather than going by the status alone, which misses an extra resource whose status write was lost.  The
+// objects are listed straight from the API server, so that no informer is started for the kinds.  The objects that
+// could not be deleted are returned as failed, so that they stay in the status and are tried again.
+func (r *ApplicationReconciler) pruneExtraResources(ctx context.Context, cr *acmeiov1beta1.Application, toReconcile []ReconcileWrapper, policy *acmepolicy.ExtraResourcePolicy) ([]acmeiov1beta1.ApplicationChildStatus, error) {
+	desired := map[string]bool{}
+	kinds := map[schema.GroupKind]schema.GroupVersionKind{}
+	for _, reconcilers := range toReconcile {
+		manifestGVK := gvk(reconcilers.Manifest)
+		desired[childKey(manifestGVK.GroupKind(), reconcilers.Manifest.GetName())] = true
+		if reconcilers.Manifest.GetLabels()[ExtraResourceLabel] == "true" {
+			kinds[manifestGVK.GroupKind()] = manifestGVK
+		}
+	}
+	for _, child := range cr.Status.Children {
+		if child.APIVersion != "" {
+			childGVK := schema.FromAPIVersionAndKind(child.APIVersion, child.Kind)
+			kinds[childGVK.GroupKind()] = childGVK
+		}
+	}
+	if policy != nil {
+		for _, allowed := range policy.AllowedKinds {
+			kind := schema.ParseGroupKind(allowed)
+			if _, ok := kinds[kind]; ok {
+				continue
+			}
+			// A kind that is not served has no objects left to prune
+			mapping, err := r.Client.RESTMapper().RESTMapping(kind)
+			if err != nil {
+				continue
+			}
+			kinds[kind] = mapping.GroupVersionKind
+		}
+	}
+
+	ordered := make([]schema.GroupVersionKind, 0, len(kinds))
+	for _, kind := range kinds {
+		ordered = append(ordered, kind)
+	}
+	sort.Slice(ordered, func(i, j int) bool { return ordered[i].String() < ordered[j].String() })
+
+	failed := []acmeiov1beta1.ApplicationChildStatus{}
+	errs := []error{}
+	for _, kind := range ordered {
+		found := &unstructured.UnstructuredList{}
+		found.SetGroupVersionKind(kind.GroupVersion().WithKind(kind.Kind + "List"))
+		if err := r.reader().List(ctx, found, client.InNamespace(cr.GetNamespace()), client.MatchingLabels{ExtraResourceLabel: "true"}); err != nil {
+			if meta.IsNoMatchError(err) {
+				continue
+			}
+			errs = append(errs, fmt.Errorf("unable to list the extra resources of kind %s: %w", kind.GroupKind(), err))
+			continue
+		}
+
+		for i := range found.Items {
+			obj := &found.Items[i]
+			if desired[childKey(kind.GroupKind(), obj.GetName())] || !metav1.IsControlledBy(obj, cr) {
+				continue
+			}
+			if err := r.Client.Delete(ctx, obj); err != nil && !errors.IsNotFound(err) {
+				child := acmeiov1beta1.ApplicationChildStatus{APIVersion: kind.GroupVersion().String(), Kind: kind.Kind, Name: obj.GetName()}
+				failed = append(failed, failedChild(child, err))
+				errs = append(errs, fmt.Errorf("unable to delete %s %s: %w", kind.Kind, obj.GetName(), err))
+			}
+		}
+	}
+
+	return failed, utilerrors.NewAggregate(errs)
+}
+
+// childKey identifies a downstream object of the CR by its kind and name
+func childKey(kind schema.GroupKind, name string) string {
+	return kind.String() + "/" + name
+}
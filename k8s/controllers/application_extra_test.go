@@ -0,0 +1,205 @@
+/*
+Copyright 2023 Nathan Brophy.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta/testrestmapper"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	acmeiov1beta1 "github.com/nathanbrophy/portfolio-demo/k8s/api/v1beta1"
+	acmegdrift "github.com/nathanbrophy/portfolio-demo/k8s/driftDetection"
+	acmegenerators "github.com/nathanbrophy/portfolio-demo/k8s/generators"
+	acmepolicy "github.com/nathanbrophy/portfolio-demo/k8s/policy"
+	acmetest "github.com/nathanbrophy/portfolio-demo/k8s/utils/test"
+)
+
+func TestWithExtraResources(t *testing.T) {
+	policy := &acmepolicy.ExtraResourcePolicy{AllowedKinds: []string{"ConfigMap", "Service"}}
+
+	tests := []struct {
+		name           string
+		extraResources []string
+		policy         *acmepolicy.ExtraResourcePolicy
+		wantExtra      int
+		wantErr        bool
+	}{
+		{
+			name: "no extra resources",
+		},
+		{
+			name:           "allowed kind",
+			extraResources: []string{`{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "settings", "labels": {"tier": "backend"}}, "data": {"mode": "fast"}}`},
+			policy:         policy,
+			wantExtra:      1,
+		},
+		{
+			name:           "kind no longer allowed",
+			extraResources: []string{`{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "settings"}}`},
+			wantErr:        true,
+		},
+		{
+			name:           "another namespace",
+			extraResources: []string{`{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "settings", "namespace": "kube-system"}}`},
+			policy:         policy,
+			wantErr:        true,
+		},
+		{
+			name:           "same kind and name as a generated object",
+			extraResources: []string{`{"apiVersion": "v1", "kind": "Service", "metadata": {"name": "` + acmeiov1beta1.NAME + `"}}`},
+			policy:         policy,
+			wantErr:        true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cr := acmetest.GenerateCRWithDefaults().(*acmeiov1beta1.Application)
+			for _, raw := range tt.extraResources {
+				cr.Spec.ExtraResources = append(cr.Spec.ExtraResources, runtime.RawExtension{Raw: []byte(raw)})
+			}
+
+			generated := manifests(acmegenerators.DefaultRegistry, cr)
+			toReconcile, err := withExtraResources(generated, cr, tt.policy)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("withExtraResources() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := len(toReconcile) - len(generated); got != tt.wantExtra {
+				t.Fatalf("withExtraResources() added %d objects, want %d", got, tt.wantExtra)
+			}
+
+			for _, reconcilers := range toReconcile[len(generated):] {
+				labels := reconcilers.Manifest.GetLabels()
+				if labels[ExtraResourceLabel] != "true" || labels[acmegenerators.ManagedByLabel] != acmegenerators.ManagedBy || labels["tier"] != "backend" {
+					t.Errorf("extra resource labels = %v, want its own labels along with the extra resource and managed-by labels", labels)
+				}
+				if gvk(reconcilers.ObjectLoader) != gvk(reconcilers.Manifest) {
+					t.Errorf("extra resource loads into a %v, want a %v", gvk(reconcilers.ObjectLoader), gvk(reconcilers.Manifest))
+				}
+
+				applied := reconcilers.Manifest.DeepCopyObject().(client.Object)
+				if err := acmegdrift.Stamp(applied); err != nil {
+					t.Fatalf("Stamp() error = %v", err)
+				}
+				if report, err := reconcilers.Driftor(reconcilers.Manifest, applied); err != nil || report.Drifted() {
+					t.Errorf("extra resource drift = %s, error = %v, want no drift from the applied manifest", report, err)
+				}
+
+				// An edit to a field of the extra resource is drift, as on a generated object
+				edited := applied.(*unstructured.Unstructured)
+				if err := unstructured.SetNestedField(edited.Object, "slow", "data", "mode"); err != nil {
+					t.Fatalf("SetNestedField() error = %v", err)
+				}
+				if report, err := reconcilers.Driftor(reconcilers.Manifest, edited); err != nil || !report.Drifted() {
+					t.Errorf("edited extra resource drift = %s, error = %v, want the edit reported", report, err)
+				}
+			}
+		})
+	}
+}
+
+func TestPruneExtraResources(t *testing.T) {
+	isController := true
+	cr := &acmeiov1beta1.Application{
+		ObjectMeta: metav1.ObjectMeta{Name: "example", Namespace: "example-ns", UID: types.UID("application-uid")},
+		Status: acmeiov1beta1.ApplicationStatus{
+			Children: []acmeiov1beta1.ApplicationChildStatus{
+				{APIVersion: "v1", Kind: "ConfigMap", Name: "kept"},
+				{APIVersion: "v1", Kind: "ConfigMap", Name: "removed"},
+				{APIVersion: "v1", Kind: "ConfigMap", Name: "generated"},
+				{APIVersion: "v1", Kind: "ConfigMap", Name: "taken-over"},
+				{APIVersion: "v1", Kind: "ConfigMap", Name: "already-gone"},
+				{Kind: "ConfigMap", Name: "recorded-without-an-api-version"},
+				{APIVersion: "batch/v1", Kind: "Job", Name: "kind-no-longer-allowed"},
+			},
+		},
+	}
+	configMap := func(name string, extra bool, controller types.UID) *corev1.ConfigMap {
+		cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: cr.GetNamespace(), Name: name}}
+		if extra {
+			cm.SetLabels(map[string]string{ExtraResourceLabel: "true"})
+		}
+		if controller != "" {
+			cm.SetOwnerReferences([]metav1.OwnerReference{{APIVersion: "acme.io/v1beta1", Kind: "Application", Name: "example", UID: controller, Controller: &isController}})
+		}
+		return cm
+	}
+
+	cronJob := &batchv1.CronJob{ObjectMeta: configMap("status-write-lost", true, cr.GetUID()).ObjectMeta}
+	job := &batchv1.Job{ObjectMeta: configMap("kind-no-longer-allowed", true, cr.GetUID()).ObjectMeta}
+
+	r := &ApplicationReconciler{
+		Client: fake.NewClientBuilder().
+			WithScheme(clientgoscheme.Scheme).
+			WithRESTMapper(testrestmapper.TestOnlyStaticRESTMapper(clientgoscheme.Scheme)).
+			WithObjects(
+				configMap("kept", true, cr.GetUID()),
+				configMap("removed", true, cr.GetUID()),
+				configMap("generated", false, cr.GetUID()),
+				configMap("taken-over", true, "other-uid"),
+				configMap("recorded-without-an-api-version", true, cr.GetUID()),
+				cronJob,
+				job,
+			).Build(),
+	}
+	policy := &acmepolicy.ExtraResourcePolicy{AllowedKinds: []string{"ConfigMap", "CronJob.batch"}}
+
+	kept := &unstructured.Unstructured{}
+	kept.SetAPIVersion("v1")
+	kept.SetKind("ConfigMap")
+	kept.SetName("kept")
+	failed, err := r.pruneExtraResources(context.Background(), cr, []ReconcileWrapper{{Manifest: kept}}, policy)
+	if err != nil || len(failed) != 0 {
+		t.Fatalf("pruneExtraResources() = %v, %v, want every removed extra resource deleted", failed, err)
+	}
+
+	wantExists := map[string]bool{
+		"kept":                            true,
+		"removed":                         false,
+		"generated":                       true,
+		"taken-over":                      true,
+		"recorded-without-an-api-version": false,
+	}
+	for name, want := range wantExists {
+		err := r.Client.Get(context.Background(), client.ObjectKey{Namespace: cr.GetNamespace(), Name: name}, &corev1.ConfigMap{})
+		if exists := !errors.IsNotFound(err); exists != want {
+			t.Errorf("ConfigMap %s exists = %v, want %v", name, exists, want)
+		}
+	}
+
+	// An extra resource missing from the status is found by its label, and one
+	// of a kind the operator no longer allows by the kind recorded in the status
+	if err := r.Client.Get(context.Background(), client.ObjectKeyFromObject(cronJob), &batchv1.CronJob{}); !errors.IsNotFound(err) {
+		t.Errorf("CronJob %s Get() error = %v, want it deleted although the status does not list it", cronJob.GetName(), err)
+	}
+	if err := r.Client.Get(context.Background(), client.ObjectKeyFromObject(job), &batchv1.Job{}); !errors.IsNotFound(err) {
+		t.Errorf("Job %s Get() error = %v, want it deleted although its kind is no longer allowed", job.GetName(), err)
+	}
+}
@@ -0,0 +1,168 @@
+/*
+Copyright 2023 Nathan Brophy.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	acmeiov1beta1 "github.com/nathanbrophy/portfolio-demo/k8s/api/v1beta1"
+)
+
+const (
+	// FinalizerName holds the deletion of an Application until its deletion policy has been carried out
+	FinalizerName string = "acme.io/finalizer"
+
+	// RetainedByAnnotation is set on the downstream objects retained after their Application was deleted, and names that Application
+	RetainedByAnnotation string = "acme.io/retained-by"
+)
+
+// finalize carries out the deletion policy of a deleted CR, and then releases the CR to be removed from the
+// cluster.  A non zero duration is returned while the pre-delete drain is still waiting on the endpoints.
+func (r *ApplicationReconciler) finalize(ctx context.Context, logger logr.Logger, cr *acmeiov1beta1.Application) (time.Duration, error) {
+	if !controllerutil.ContainsFinalizer(cr, FinalizerName) {
+		return 0, nil
+	}
+
+	toFinalize := manifests(r.generators(), cr)
+	for _, reconcilers := range toFinalize {
+		reconcilers.Manifest.SetNamespace(cr.GetNamespace())
+	}
+
+	if cr.PreDeleteDrain() {
+		drained, err := r.drain(ctx, cr, toFinalize)
+		if err != nil {
+			return 0, err
+		}
+		if !drained {
+			if time.Since(cr.GetDeletionTimestamp().Time) < cr.PreDeleteTimeout() {
+				logger.Info("waiting for the endpoints to drain before removing the downstream objects")
+				r.Recorder.Event(cr, corev1.EventTypeNormal, "Draining", "waiting for the endpoints to drain before removing the downstream objects")
+				return time.Second * 5, nil
+			}
+			logger.Info("timed out waiting for the endpoints to drain, removing the downstream objects regardless")
+			r.Recorder.Eventf(cr, corev1.EventTypeWarning, "DrainTimedOut", "the endpoints did not drain within %s, removing the downstream objects regardless", cr.PreDeleteTimeout())
+		}
+	}
+
+	// Objects that are deleted along with the CR are left to the garbage collector,
+	// which follows the owner references once the finalizer has been removed.
+	for _, reconcilers := range toFinalize {
+		policy := cr.DeletionPolicyFor(gvk(reconcilers.Manifest).Kind)
+		if policy == acmeiov1beta1.DeletionPolicyDelete {
+			continue
+		}
+		if err := r.release(ctx, cr, reconcilers, policy); err != nil {
+			return 0, err
+		}
+		logger.Info("released a downstream object from the CR", "kind", gvk(reconcilers.Manifest).Kind, "name", reconcilers.Manifest.GetName(), "policy", policy)
+		r.Recorder.Eventf(cr, corev1.EventTypeNormal, "Released", "released %s %s from the Application as the deletion policy is %s", gvk(reconcilers.Manifest).Kind, reconcilers.Manifest.GetName(), policy)
+	}
+
+	controllerutil.RemoveFinalizer(cr, FinalizerName)
+
+	return 0, r.Client.Update(ctx, cr)
+}
+
+// drain scales the Deployment down to zero replicas, and reports if the endpoints of the Service have drained.  A
+// Deployment that is retained or orphaned keeps serving once the CR is gone, so it is neither scaled down nor waited on.
+func (r *ApplicationReconciler) drain(ctx context.Context, cr *acmeiov1beta1.Application, toFinalize []ReconcileWrapper) (bool, error) {
+	if cr.DeletionPolicyFor("Deployment") != acmeiov1beta1.DeletionPolicyDelete {
+		return true, nil
+	}
+
+	drained := true
+
+	for _, reconcilers := range toFinalize {
+		switch manifest := reconcilers.Manifest.(type) {
+		case *appsv1.Deployment:
+			found := &appsv1.Deployment{}
+			found.SetNamespace(manifest.GetNamespace())
+			found.SetName(manifest.GetName())
+			if err := r.getChild(ctx, found); err != nil {
+				if errors.IsNotFound(err) {
+					continue
+				}
+				return false, err
+			}
+			if found.Spec.Replicas == nil || *found.Spec.Replicas != 0 {
+				replicas := int32(0)
+				found.Spec.Replicas = &replicas
+				if err := r.Client.Update(ctx, found); err != nil {
+					return false, err
+				}
+			}
+		case *corev1.Service:
+			endpoints := &corev1.Endpoints{}
+			if err := r.reader().Get(ctx, client.ObjectKeyFromObject(manifest), endpoints); err != nil {
+				if errors.IsNotFound(err) {
+					continue
+				}
+				return false, err
+			}
+			// Terminating pods are listed as not ready addresses until they are gone
+			for _, subset := range endpoints.Subsets {
+				if len(subset.Addresses) > 0 || len(subset.NotReadyAddresses) > 0 {
+					drained = false
+				}
+			}
+		}
+	}
+
+	return drained, nil
+}
+
+// release detaches a downstream object from the CR so that the garbage collector leaves it on the cluster, a retained
+// object is also marked with the name of the CR so that an Application of the same name adopts it again
+func (r *ApplicationReconciler) release(ctx context.Context, cr *acmeiov1beta1.Application, reconcilers ReconcileWrapper, policy acmeiov1beta1.DeletionPolicy) error {
+	found := reconcilers.ObjectLoader
+	found.SetNamespace(reconcilers.Manifest.GetNamespace())
+	found.SetName(reconcilers.Manifest.GetName())
+	if err := r.getChild(ctx, found); err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	owners := []metav1.OwnerReference{}
+	for _, owner := range found.GetOwnerReferences() {
+		if owner.UID != cr.GetUID() {
+			owners = append(owners, owner)
+		}
+	}
+	found.SetOwnerReferences(owners)
+
+	if policy == acmeiov1beta1.DeletionPolicyRetain {
+		annotations := found.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[RetainedByAnnotation] = cr.GetName()
+		found.SetAnnotations(annotations)
+	}
+
+	return r.Client.Update(ctx, found)
+}
@@ -0,0 +1,231 @@
+/*
+Copyright 2023 Nathan Brophy.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	acmeiov1beta1 "github.com/nathanbrophy/portfolio-demo/k8s/api/v1beta1"
+)
+
+// deleteApplication reconciles the CR into existence, and then deletes it, which is held by the finalizer
+func deleteApplication(t *testing.T, cluster *fakeCluster, cr *acmeiov1beta1.Application) {
+	t.Helper()
+
+	if _, err := cluster.reconcile(t, cr); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	if err := cluster.reconciler.Client.Delete(context.Background(), cluster.application(t, cr)); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+}
+
+func TestReconcile_Finalize(t *testing.T) {
+	tests := []struct {
+		policy       acmeiov1beta1.DeletionPolicy
+		wantOwned    bool
+		wantRetained bool
+	}{
+		{policy: acmeiov1beta1.DeletionPolicyDelete, wantOwned: true},
+		{policy: acmeiov1beta1.DeletionPolicyOrphan},
+		{policy: acmeiov1beta1.DeletionPolicyRetain, wantRetained: true},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			cr := reconcilingCR("finalize")
+			cr.Spec.DeletionPolicy = &acmeiov1beta1.ApplicationDeletionPolicy{Default: tt.policy}
+			cluster := newFakeCluster(t, cr)
+			deleteApplication(t, cluster, cr)
+
+			if result, err := cluster.reconcile(t, cr); err != nil || result.RequeueAfter != 0 {
+				t.Fatalf("Reconcile() = %v, %v, want the deletion policy carried out at once", result, err)
+			}
+			err := cluster.reconciler.Client.Get(context.Background(), client.ObjectKeyFromObject(cr), &acmeiov1beta1.Application{})
+			if !errors.IsNotFound(err) {
+				t.Errorf("Get() error = %v, want the CR removed once its finalizer is", err)
+			}
+
+			// Deleted objects are left to the garbage collector, which follows the
+			// owner references the released objects no longer have
+			for _, obj := range []client.Object{&appsv1.Deployment{}, &corev1.Service{}} {
+				if err := cluster.reconciler.Client.Get(context.Background(), client.ObjectKey{Namespace: cr.GetNamespace(), Name: acmeiov1beta1.NAME}, obj); err != nil {
+					t.Fatalf("Get() error = %v", err)
+				}
+				if owned := len(obj.GetOwnerReferences()) > 0; owned != tt.wantOwned {
+					t.Errorf("%T owned = %v, want %v", obj, owned, tt.wantOwned)
+				}
+				if retained := obj.GetAnnotations()[RetainedByAnnotation] == cr.GetName(); retained != tt.wantRetained {
+					t.Errorf("%T retained = %v, want %v", obj, retained, tt.wantRetained)
+				}
+			}
+		})
+	}
+}
+
+func TestDrain(t *testing.T) {
+	tests := []struct {
+		name         string
+		policy       acmeiov1beta1.DeletionPolicy
+		addresses    []corev1.EndpointAddress
+		wantDrained  bool
+		wantReplicas int32
+	}{
+		{
+			name:         "endpoints still serving",
+			policy:       acmeiov1beta1.DeletionPolicyDelete,
+			addresses:    []corev1.EndpointAddress{{IP: "10.0.0.1"}},
+			wantDrained:  false,
+			wantReplicas: 0,
+		},
+		{
+			name:         "endpoints drained",
+			policy:       acmeiov1beta1.DeletionPolicyDelete,
+			wantDrained:  true,
+			wantReplicas: 0,
+		},
+		{
+			name:         "retained Deployment",
+			policy:       acmeiov1beta1.DeletionPolicyRetain,
+			addresses:    []corev1.EndpointAddress{{IP: "10.0.0.1"}},
+			wantDrained:  true,
+			wantReplicas: 3,
+		},
+		{
+			name:         "orphaned Deployment",
+			policy:       acmeiov1beta1.DeletionPolicyOrphan,
+			addresses:    []corev1.EndpointAddress{{IP: "10.0.0.1"}},
+			wantDrained:  true,
+			wantReplicas: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cr := reconcilingCR("drain")
+			cr.Spec.Application.Replicas = func(x int32) *int32 { return &x }(3)
+			cr.Spec.DeletionPolicy = &acmeiov1beta1.ApplicationDeletionPolicy{
+				Kinds:     []acmeiov1beta1.ApplicationKindDeletionPolicy{{Kind: "Deployment", Policy: tt.policy}},
+				PreDelete: &acmeiov1beta1.ApplicationPreDelete{Drain: true},
+			}
+			endpoints := &corev1.Endpoints{
+				ObjectMeta: metav1.ObjectMeta{Namespace: cr.GetNamespace(), Name: acmeiov1beta1.NAME},
+				Subsets:    []corev1.EndpointSubset{{Addresses: tt.addresses}},
+			}
+			cluster := newFakeCluster(t, cr, endpoints)
+			if _, err := cluster.reconcile(t, cr); err != nil {
+				t.Fatalf("Reconcile() error = %v", err)
+			}
+
+			toFinalize := manifests(cluster.reconciler.generators(), cr)
+			for _, reconcilers := range toFinalize {
+				reconcilers.Manifest.SetNamespace(cr.GetNamespace())
+			}
+			drained, err := cluster.reconciler.drain(context.Background(), cr, toFinalize)
+			if err != nil {
+				t.Fatalf("drain() error = %v", err)
+			}
+			if drained != tt.wantDrained {
+				t.Errorf("drain() = %v, want %v", drained, tt.wantDrained)
+			}
+
+			deployment := &appsv1.Deployment{}
+			if err := cluster.reconciler.Client.Get(context.Background(), client.ObjectKey{Namespace: cr.GetNamespace(), Name: acmeiov1beta1.NAME}, deployment); err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			if got := *deployment.Spec.Replicas; got != tt.wantReplicas {
+				t.Errorf("Deployment replicas = %v, want %v", got, tt.wantReplicas)
+			}
+		})
+	}
+}
+
+func TestReconcile_FinalizeDraining(t *testing.T) {
+	cr := reconcilingCR("draining")
+	cr.Spec.DeletionPolicy = &acmeiov1beta1.ApplicationDeletionPolicy{PreDelete: &acmeiov1beta1.ApplicationPreDelete{Drain: true}}
+	endpoints := &corev1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{Namespace: cr.GetNamespace(), Name: acmeiov1beta1.NAME},
+		Subsets:    []corev1.EndpointSubset{{NotReadyAddresses: []corev1.EndpointAddress{{IP: "10.0.0.1"}}}},
+	}
+	cluster := newFakeCluster(t, cr, endpoints)
+	deleteApplication(t, cluster, cr)
+
+	// The CR is held while a terminating pod is still listed
+	if result, err := cluster.reconcile(t, cr); err != nil || result.RequeueAfter == 0 {
+		t.Fatalf("Reconcile() = %v, %v, want a requeue while the endpoints drain", result, err)
+	}
+	if found := cluster.application(t, cr); !controllerutil.ContainsFinalizer(found, FinalizerName) {
+		t.Errorf("finalizers = %v, want the CR held while the endpoints drain", found.GetFinalizers())
+	}
+	if events := strings.Join(cluster.events(), "\n"); !strings.Contains(events, "Draining") {
+		t.Errorf("events = %q, want a Draining event", events)
+	}
+
+	endpoints.Subsets = nil
+	if err := cluster.reconciler.Client.Update(context.Background(), endpoints); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if result, err := cluster.reconcile(t, cr); err != nil || result.RequeueAfter != 0 {
+		t.Fatalf("Reconcile() = %v, %v, want the CR released once the endpoints drained", result, err)
+	}
+	err := cluster.reconciler.Client.Get(context.Background(), client.ObjectKeyFromObject(cr), &acmeiov1beta1.Application{})
+	if !errors.IsNotFound(err) {
+		t.Errorf("Get() error = %v, want the CR removed once the endpoints drained", err)
+	}
+}
+
+func TestRelease(t *testing.T) {
+	isController := true
+	cr := reconcilingCR("release")
+	other := metav1.OwnerReference{APIVersion: "v1", Kind: "ConfigMap", Name: "other", UID: "other-uid"}
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: cr.GetNamespace(),
+			Name:      acmeiov1beta1.NAME,
+			OwnerReferences: []metav1.OwnerReference{
+				{APIVersion: "acme.io/v1beta1", Kind: "Application", Name: cr.GetName(), UID: cr.GetUID(), Controller: &isController},
+				other,
+			},
+		},
+	}
+	cluster := newFakeCluster(t, cr, service)
+
+	for _, reconcilers := range manifests(cluster.reconciler.generators(), cr) {
+		reconcilers.Manifest.SetNamespace(cr.GetNamespace())
+		// Only the Service exists, the other objects leave nothing to release
+		if err := cluster.reconciler.release(context.Background(), cr, reconcilers, acmeiov1beta1.DeletionPolicyRetain); err != nil {
+			t.Fatalf("release() of the %s error = %v", gvk(reconcilers.Manifest).Kind, err)
+		}
+	}
+
+	if err := cluster.reconciler.Client.Get(context.Background(), client.ObjectKeyFromObject(service), service); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if owners := service.GetOwnerReferences(); len(owners) != 1 || owners[0].UID != other.UID {
+		t.Errorf("owner references = %v, want only the owners other than the CR", owners)
+	}
+	if got := service.GetAnnotations()[RetainedByAnnotation]; got != cr.GetName() {
+		t.Errorf("%s annotation = %q, want %q", RetainedByAnnotation, got, cr.GetName())
+	}
+}
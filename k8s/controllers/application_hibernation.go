@@ -0,0 +1,69 @@
+/*
+Copyright 2023 Nathan Brophy.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	acmeapi "github.com/nathanbrophy/portfolio-demo/k8s/api"
+	acmeiov1beta1 "github.com/nathanbrophy/portfolio-demo/k8s/api/v1beta1"
+	acmehibernation "github.com/nathanbrophy/portfolio-demo/k8s/hibernation"
+)
+
+// replicasOverride decorates the CR so that the generators render the given replica count in place of the replicas in the spec
+type replicasOverride struct {
+	acmeapi.Application
+	replicas int32
+}
+
+func (r *replicasOverride) Replicas() *int32 {
+	replicas := r.replicas
+	return &replicas
+}
+
+// withHibernation records the hibernation state in the status of the CR, a zero next transition clears it
+func withHibernation(hibernating bool, next time.Time) statusMutator {
+	return func(status *acmeiov1beta1.ApplicationStatus) {
+		status.Hibernating = hibernating
+		status.NextHibernationTransition = nil
+		if !next.IsZero() {
+			status.NextHibernationTransition = &metav1.Time{Time: next}
+		}
+	}
+}
+
+// hibernate scales the CR down to zero replicas while it is inside its hibernation window, and returns the time of
+// the next transition of the window.  A CR without a hibernation window is returned as is with a zero transition time.
+func hibernate(in acmeapi.Application, hibernation *acmeiov1beta1.ApplicationHibernation, now time.Time) (acmeapi.Application, bool, time.Time, error) {
+	if hibernation == nil {
+		return in, false, time.Time{}, nil
+	}
+
+	window, err := acmehibernation.Parse(hibernation.Sleep, hibernation.Wake, hibernation.TimeZone)
+	if err != nil {
+		return nil, false, time.Time{}, err
+	}
+
+	asleep, next := window.State(now)
+	if asleep {
+		return &replicasOverride{Application: in, replicas: 0}, true, next, nil
+	}
+
+	return in, false, next, nil
+}
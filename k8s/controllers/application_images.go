@@ -0,0 +1,175 @@
+/*
+Copyright 2023 Nathan Brophy.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	acmeapi "github.com/nathanbrophy/portfolio-demo/k8s/api"
+	acmeiov1beta1 "github.com/nathanbrophy/portfolio-demo/k8s/api/v1beta1"
+	acmepolicy "github.com/nathanbrophy/portfolio-demo/k8s/policy"
+	acmeregistry "github.com/nathanbrophy/portfolio-demo/k8s/registry"
+)
+
+// imageOverride decorates the CR so that the generators render the given image in place of the image in the spec
+type imageOverride struct {
+	acmeapi.Application
+	image string
+}
+
+func (i *imageOverride) Image() string {
+	return i.image
+}
+
+// mirrorOverride decorates the CR so that the generators render the image pulled through the matching
+// registry mirror, with the pull secret for that mirror bound to the generated service account
+type mirrorOverride struct {
+	acmeapi.Application
+	image      string
+	pullSecret string
+}
+
+func (m *mirrorOverride) Image() string {
+	return m.image
+}
+
+func (m *mirrorOverride) ImagePullSecrets() []string {
+	pullSecrets := m.Application.ImagePullSecrets()
+	if m.pullSecret == "" {
+		return pullSecrets
+	}
+	for _, name := range pullSecrets {
+		if name == m.pullSecret {
+			return pullSecrets
+		}
+	}
+
+	return append(append([]string{}, pullSecrets...), m.pullSecret)
+}
+
+// mirror rewrites the image of the CR to the first matching registry mirror, the CR is returned as is when
+// no mirror rule matches its image
+func mirror(in acmeapi.Application, mirrors acmeregistry.Mirrors) acmeapi.Application {
+	image, mirror := mirrors.Rewrite(in.Image())
+	if mirror == nil {
+		return in
+	}
+
+	return &mirrorOverride{Application: in, image: image, pullSecret: mirror.PullSecret}
+}
+
+// withImage records the image digest pinning result in the status of the CR, empty values clear it
+func withImage(resolved, digest string) statusMutator {
+	return func(status *acmeiov1beta1.ApplicationStatus) {
+		status.ResolvedImage = resolved
+		status.ImageDigest = digest
+	}
+}
+
+// keychain loads the registry credentials from the image pull secrets of the CR, secrets that do not
+// exist are skipped in the same way the kubelet skips them when pulling
+func (r *ApplicationReconciler) keychain(ctx context.Context, namespace string, in acmeapi.Application) (acmeregistry.Keychain, error) {
+	keychain := acmeregistry.Keychain{}
+
+	for _, name := range in.ImagePullSecrets() {
+		secret := &corev1.Secret{}
+		if err := r.reader().Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, secret); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		found, err := acmeregistry.KeychainFromSecret(secret)
+		if err != nil {
+			return nil, err
+		}
+		keychain.Merge(found)
+	}
+
+	return keychain, nil
+}
+
+// pinImage resolves the image of the CR to the digest it currently points at, and returns the image
+// reference pinned to that digest along with the digest itself.  The digest recorded in the status is reused
+// for as long as the generation of the CR is unchanged, so that a tag moved in the registry does not roll
+// out the Deployment on a resync, and the registry is not queried on every reconciliation.
+func (r *ApplicationReconciler) pinImage(ctx context.Context, cr *acmeiov1beta1.Application, in acmeapi.Application) (string, string, error) {
+	ref, err := acmeregistry.ParseReference(in.Image())
+	if err != nil {
+		return "", "", err
+	}
+
+	status := cr.Status
+	if status.ObservedGeneration == cr.GetGeneration() && status.ImageDigest != "" && status.ResolvedImage == ref.Pinned(status.ImageDigest) {
+		return status.ResolvedImage, status.ImageDigest, nil
+	}
+
+	if r.Resolver == nil {
+		return "", "", fmt.Errorf("image digest pinning is enabled, but the controller has no registry resolver configured")
+	}
+
+	keychain, err := r.keychain(ctx, cr.GetNamespace(), in)
+	if err != nil {
+		return "", "", err
+	}
+
+	digest, err := r.Resolver.Resolve(ctx, in.Image(), keychain)
+	if err != nil {
+		return "", "", fmt.Errorf("cannot resolve image %s to a digest: %w", in.Image(), err)
+	}
+
+	return ref.Pinned(digest), digest, nil
+}
+
+// checkImagePolicy validates the image of the CR, and every container image in the manifests to reconcile, against
+// the operator image policy.  Both are checked as a pinned image no longer carries the tag that was asked for.
+// Images rewritten to a registry mirror are judged on the source registry they were rewritten from.
+func checkImagePolicy(policy *acmepolicy.ImagePolicy, mirrors acmeregistry.Mirrors, in acmeapi.Application, toReconcile []ReconcileWrapper) error {
+	images := []string{in.Image()}
+	for _, reconcilers := range toReconcile {
+		found, err := containerImages(reconcilers.Manifest)
+		if err != nil {
+			return err
+		}
+		images = append(images, found...)
+	}
+
+	for _, image := range images {
+		if err := policy.Validate(mirrors.Restore(image)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// containerImages lists the image of every container in any pod spec the object holds, see acmepolicy.ContainerImages
+func containerImages(obj client.Object) ([]string, error) {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj.DeepCopyObject())
+	if err != nil {
+		return nil, err
+	}
+
+	return acmepolicy.ContainerImages(content), nil
+}
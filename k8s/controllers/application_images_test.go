@@ -0,0 +1,230 @@
+/*
+Copyright 2023 Nathan Brophy.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	acmeiov1beta1 "github.com/nathanbrophy/portfolio-demo/k8s/api/v1beta1"
+	acmegenerators "github.com/nathanbrophy/portfolio-demo/k8s/generators"
+	acmeoperatorconfig "github.com/nathanbrophy/portfolio-demo/k8s/operatorconfig"
+	acmepolicy "github.com/nathanbrophy/portfolio-demo/k8s/policy"
+	acmeregistry "github.com/nathanbrophy/portfolio-demo/k8s/registry"
+	acmetest "github.com/nathanbrophy/portfolio-demo/k8s/utils/test"
+)
+
+func TestCheckImagePolicy(t *testing.T) {
+	allowlist := &acmepolicy.ImagePolicy{AllowedRegistries: []string{"example.com"}}
+
+	tests := []struct {
+		name      string
+		policy    *acmepolicy.ImagePolicy
+		mirrors   acmeregistry.Mirrors
+		overrides []acmeiov1beta1.ApplicationOverride
+		extra     string
+		wantErr   bool
+	}{
+		{
+			name:   "no mirror",
+			policy: allowlist,
+		},
+		{
+			name:    "mirrored image judged on its source registry",
+			policy:  allowlist,
+			mirrors: acmeregistry.Mirrors{{Source: "example.com", Mirror: "mirror.acme.internal/example"}},
+		},
+		{
+			name:    "source registry that is not allowed",
+			policy:  &acmepolicy.ImagePolicy{AllowedRegistries: []string{"mirror.acme.internal"}},
+			mirrors: acmeregistry.Mirrors{{Source: "example.com", Mirror: "mirror.acme.internal/example"}},
+			wantErr: true,
+		},
+		{
+			name:   "init container added by an override",
+			policy: allowlist,
+			overrides: []acmeiov1beta1.ApplicationOverride{{
+				Kind:  "Deployment",
+				Patch: "spec:\n  template:\n    spec:\n      initContainers:\n      - name: setup\n        image: docker.io/library/busybox:1.36.0\n",
+			}},
+			wantErr: true,
+		},
+		{
+			name:   "container added by a JSON6902 override",
+			policy: allowlist,
+			overrides: []acmeiov1beta1.ApplicationOverride{{
+				Kind:  "Deployment",
+				Type:  acmeiov1beta1.OverridePatchTypeJSON6902,
+				Patch: `[{"op": "add", "path": "/spec/template/spec/containers/-", "value": {"name": "sidecar", "image": "quay.io/other/sidecar:v1.0"}}]`,
+			}},
+			wantErr: true,
+		},
+		{
+			name:    "extra resource with an image from a registry that is not allowed",
+			policy:  allowlist,
+			extra:   `{"apiVersion": "batch/v1", "kind": "CronJob", "metadata": {"name": "cleanup"}, "spec": {"jobTemplate": {"spec": {"template": {"spec": {"containers": [{"name": "cleanup", "image": "quay.io/other/cleanup:v1.0"}]}}}}}}`,
+			wantErr: true,
+		},
+		{
+			name:   "extra resource with an allowed image",
+			policy: allowlist,
+			extra:  `{"apiVersion": "batch/v1", "kind": "Job", "metadata": {"name": "migrate"}, "spec": {"template": {"spec": {"containers": [{"name": "migrate", "image": "example.com/migrate:v1.0"}]}}}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cr := acmetest.GenerateCRWithDefaults().(*acmeiov1beta1.Application)
+			cr.Spec.Overrides = tt.overrides
+			if tt.extra != "" {
+				cr.Spec.ExtraResources = []runtime.RawExtension{{Raw: []byte(tt.extra)}}
+			}
+
+			toReconcile, err := overriding(manifests(acmegenerators.DefaultRegistry, mirror(cr, tt.mirrors)), cr)
+			if err != nil {
+				t.Fatalf("overriding() error = %v", err)
+			}
+			toReconcile, err = withExtraResources(toReconcile, cr, &acmepolicy.ExtraResourcePolicy{AllowedKinds: []string{"CronJob.batch", "Job.batch"}})
+			if err != nil {
+				t.Fatalf("withExtraResources() error = %v", err)
+			}
+			if err := checkImagePolicy(tt.policy, tt.mirrors, cr, toReconcile); (err != nil) != tt.wantErr {
+				t.Errorf("checkImagePolicy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// movingTagResolver resolves every image to the digest its tag currently points at, which moves on every push
+type movingTagResolver struct {
+	digest   string
+	resolved int
+}
+
+func (m *movingTagResolver) Resolve(ctx context.Context, image string, keychain acmeregistry.Keychain) (string, error) {
+	m.resolved++
+	return m.digest, nil
+}
+
+func TestReconcile_PinImage(t *testing.T) {
+	cr := reconcilingCR("pinned")
+	cr.Spec.Application.PinImageDigest = func(x bool) *bool { return &x }(true)
+	resolver := &movingTagResolver{digest: "sha256:aaaa"}
+	cluster := newFakeCluster(t, cr)
+	cluster.reconciler.Resolver = resolver
+
+	image := func() string {
+		t.Helper()
+		deployment := &appsv1.Deployment{}
+		if err := cluster.reconciler.Client.Get(context.Background(), client.ObjectKey{Namespace: cr.GetNamespace(), Name: acmeiov1beta1.NAME}, deployment); err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		return deployment.Spec.Template.Spec.Containers[0].Image
+	}
+
+	if _, err := cluster.reconcile(t, cr); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	if got := image(); got != "example.com/test-image@sha256:aaaa" {
+		t.Fatalf("Deployment image = %v, want the image pinned to its digest", got)
+	}
+
+	// The tag is pushed again, which is not picked up by a resync of the same generation
+	resolver.digest = "sha256:bbbb"
+	if _, err := cluster.reconcile(t, cr); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	if got := image(); got != "example.com/test-image@sha256:aaaa" || resolver.resolved != 1 {
+		t.Errorf("Deployment image = %v after %d resolutions, want the digest of the generation reused", got, resolver.resolved)
+	}
+
+	// but is by the next generation of the CR
+	cluster.update(t, cr, func(found *acmeiov1beta1.Application) {
+		found.Spec.Application.Port = func(x int32) *int32 { return &x }(8081)
+	})
+	if _, err := cluster.reconcile(t, cr); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	if got := image(); got != "example.com/test-image@sha256:bbbb" || resolver.resolved != 2 {
+		t.Errorf("Deployment image = %v after %d resolutions, want the image resolved again for a new generation", got, resolver.resolved)
+	}
+	if found := cluster.application(t, cr); found.Status.ObservedGeneration != found.GetGeneration() || found.Status.ImageDigest != "sha256:bbbb" {
+		t.Errorf("status = generation %d with digest %s, want generation %d with digest sha256:bbbb", found.Status.ObservedGeneration, found.Status.ImageDigest, found.GetGeneration())
+	}
+}
+
+func TestReconcile_PinDeniedImage(t *testing.T) {
+	cr := reconcilingCR("pinned-denied")
+	cr.Spec.Application.PinImageDigest = func(x bool) *bool { return &x }(true)
+	resolver := &movingTagResolver{digest: "sha256:aaaa"}
+	cluster := newFakeCluster(t, cr)
+	cluster.reconciler.Resolver = resolver
+	cluster.reconciler.Config = acmeoperatorconfig.NewStore(nil)
+	cluster.reconciler.Config.Set(&acmeoperatorconfig.Config{
+		ImagePolicy: acmepolicy.ImagePolicy{AllowedRegistries: []string{"quay.io/acme"}},
+	})
+
+	if _, err := cluster.reconcile(t, cr); err == nil {
+		t.Fatalf("Reconcile() error = nil, want the image policy violation")
+	}
+	if resolver.resolved != 0 {
+		t.Errorf("resolved the image %d times, want the registry of a denied image left alone", resolver.resolved)
+	}
+	found := cluster.application(t, cr)
+	if condition := meta.FindStatusCondition(found.Status.Conditions, acmeiov1beta1.ConditionImagePolicyViolation); condition == nil || condition.Status != metav1.ConditionTrue {
+		t.Errorf("%s condition = %v, want the violation reported", acmeiov1beta1.ConditionImagePolicyViolation, condition)
+	}
+}
+
+func TestReconcile_MirrorReloaded(t *testing.T) {
+	cr := reconcilingCR("mirror-reloaded")
+	cluster := newFakeCluster(t, cr)
+	cluster.reconciler.Config = acmeoperatorconfig.NewStore(acmeoperatorconfig.FromFlags(acmepolicy.ImagePolicy{}, nil, nil))
+
+	image := func() string {
+		t.Helper()
+		deployment := &appsv1.Deployment{}
+		if err := cluster.reconciler.Client.Get(context.Background(), client.ObjectKey{Namespace: cr.GetNamespace(), Name: acmeiov1beta1.NAME}, deployment); err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		return deployment.Spec.Template.Spec.Containers[0].Image
+	}
+
+	if _, err := cluster.reconcile(t, cr); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	if got := image(); got != "example.com/test-image:v1.0" {
+		t.Fatalf("Deployment image = %v, want the image as is without a mirror", got)
+	}
+
+	// A mirror added by reloading the operator config applies from the next
+	// reconciliation on, without a restart
+	cluster.reconciler.Config.Set(&acmeoperatorconfig.Config{
+		Mirrors: acmeregistry.Mirrors{{Source: "example.com", Mirror: "mirror.acme.internal/example"}},
+	})
+	if _, err := cluster.reconcile(t, cr); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	if got := image(); got != "mirror.acme.internal/example/test-image:v1.0" {
+		t.Errorf("Deployment image = %v, want the image pulled through the reloaded mirror", got)
+	}
+}
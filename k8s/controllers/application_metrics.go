@@ -0,0 +1,167 @@
+/*
+Copyright 2023 Nathan Brophy.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	acmeiov1beta1 "github.com/nathanbrophy/portfolio-demo/k8s/api/v1beta1"
+)
+
+// The metrics about Applications are served by the manager along with the controller-runtime metrics, and every
+// series is labelled with the namespace and name of its Application so that alerts can be raised per Application.
+var (
+	childReconcileDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "acme_application_child_reconcile_duration_seconds",
+		Help:    "Time taken to reconcile a downstream object of an Application, by kind.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"namespace", "name", "kind"})
+
+	childReconcileTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "acme_application_child_reconcile_total",
+		Help: "Number of times a downstream object of an Application was reconciled, by kind and outcome.",
+	}, []string{"namespace", "name", "kind", "outcome"})
+
+	driftDetectionsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "acme_application_drift_detections_total",
+		Help: "Number of times drift was found on a downstream object of an Application, by kind.",
+	}, []string{"namespace", "name", "kind"})
+
+	applicationReady = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "acme_application_ready",
+		Help: "Whether the last reconciliation of an Application succeeded with every downstream object in sync.",
+	}, []string{"namespace", "name"})
+
+	applicationCondition = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "acme_application_status_condition",
+		Help: "The current status of each status condition of an Application, one series per condition and status.",
+	}, []string{"namespace", "name", "type", "status"})
+
+	rolloutDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "acme_application_rollout_duration_seconds",
+		Help:    "Time taken from applying a Deployment change until every replica of the Application is updated and available.",
+		Buckets: prometheus.ExponentialBuckets(5, 2, 10),
+	}, []string{"namespace", "name"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		childReconcileDuration,
+		childReconcileTotal,
+		driftDetectionsTotal,
+		applicationReady,
+		applicationCondition,
+		rolloutDuration,
+	)
+}
+
+// observeChild records the outcome of reconciling a downstream object, along with the time it took
+func observeChild(cr *acmeiov1beta1.Application, child acmeiov1beta1.ApplicationChildStatus, duration time.Duration) {
+	childReconcileDuration.WithLabelValues(cr.GetNamespace(), cr.GetName(), child.Kind).Observe(duration.Seconds())
+	childReconcileTotal.WithLabelValues(cr.GetNamespace(), cr.GetName(), child.Kind, child.Reason).Inc()
+}
+
+// observeDrift counts drift found on a downstream object
+func observeDrift(namespace, name, kind string) {
+	driftDetectionsTotal.WithLabelValues(namespace, name, kind).Inc()
+}
+
+// observeStatus records the readiness and the status conditions of the CR
+func observeStatus(key types.NamespacedName, status *acmeiov1beta1.ApplicationStatus) {
+	ready := !status.Progressing && !strings.HasPrefix(status.Reason, "failed")
+	for _, child := range status.Children {
+		ready = ready && child.Synced
+	}
+	applicationReady.WithLabelValues(key.Namespace, key.Name).Set(boolToFloat(ready))
+
+	for _, condition := range status.Conditions {
+		for _, value := range []metav1.ConditionStatus{metav1.ConditionTrue, metav1.ConditionFalse, metav1.ConditionUnknown} {
+			applicationCondition.WithLabelValues(key.Namespace, key.Name, condition.Type, string(value)).Set(boolToFloat(condition.Status == value))
+		}
+	}
+}
+
+// forgetMetrics drops every series of a deleted CR, so that it is no longer alerted on
+func (r *ApplicationReconciler) forgetMetrics(key types.NamespacedName) {
+	labels := prometheus.Labels{"namespace": key.Namespace, "name": key.Name}
+	childReconcileDuration.DeletePartialMatch(labels)
+	childReconcileTotal.DeletePartialMatch(labels)
+	driftDetectionsTotal.DeletePartialMatch(labels)
+	applicationReady.DeletePartialMatch(labels)
+	applicationCondition.DeletePartialMatch(labels)
+	rolloutDuration.DeletePartialMatch(labels)
+
+	r.rollouts.Delete(key)
+}
+
+// startRollout marks the start of a rollout of the Deployment of the CR, a rollout that is still in progress keeps its
+// original start, so that the duration covers every change applied along the way
+func (r *ApplicationReconciler) startRollout(key types.NamespacedName, now time.Time) {
+	r.rollouts.LoadOrStore(key, now)
+}
+
+// observeRollout records the duration of a rollout of the Deployment of the CR once it has completed.  The owned
+// Deployment is watched, so every change to its status brings the CR back here until the rollout completes.
+func (r *ApplicationReconciler) observeRollout(ctx context.Context, key types.NamespacedName, deploymentName string) error {
+	started, ok := r.rollouts.Load(key)
+	if !ok {
+		return nil
+	}
+
+	deployment := &appsv1.Deployment{}
+	if err := r.Client.Get(ctx, client.ObjectKey{Namespace: key.Namespace, Name: deploymentName}, deployment); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if !rolledOut(deployment) {
+		return nil
+	}
+
+	rolloutDuration.WithLabelValues(key.Namespace, key.Name).Observe(time.Since(started.(time.Time)).Seconds())
+	r.rollouts.Delete(key)
+
+	return nil
+}
+
+// rolledOut reports if every replica of the Deployment runs its latest template and is available
+func rolledOut(deployment *appsv1.Deployment) bool {
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+
+	return deployment.Status.ObservedGeneration >= deployment.Generation &&
+		deployment.Status.UpdatedReplicas == replicas &&
+		deployment.Status.AvailableReplicas == replicas &&
+		deployment.Status.Replicas == replicas
+}
+
+func boolToFloat(value bool) float64 {
+	if value {
+		return 1
+	}
+
+	return 0
+}
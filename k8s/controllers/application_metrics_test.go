@@ -0,0 +1,152 @@
+/*
+Copyright 2023 Nathan Brophy.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	acmeiov1beta1 "github.com/nathanbrophy/portfolio-demo/k8s/api/v1beta1"
+	acmeioutils "github.com/nathanbrophy/portfolio-demo/k8s/utils"
+)
+
+func TestRolledOut(t *testing.T) {
+	deployment := func(generation, observed int64, replicas, updated, available, total int32) *appsv1.Deployment {
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Generation: generation},
+			Spec:       appsv1.DeploymentSpec{Replicas: acmeioutils.Int32PointerGenerator(replicas)},
+			Status: appsv1.DeploymentStatus{
+				ObservedGeneration: observed,
+				UpdatedReplicas:    updated,
+				AvailableReplicas:  available,
+				Replicas:           total,
+			},
+		}
+	}
+
+	tests := []struct {
+		name       string
+		deployment *appsv1.Deployment
+		want       bool
+	}{
+		{
+			name:       "rolled out",
+			deployment: deployment(2, 2, 3, 3, 3, 3),
+			want:       true,
+		},
+		{
+			name:       "change not observed yet",
+			deployment: deployment(3, 2, 3, 3, 3, 3),
+			want:       false,
+		},
+		{
+			name:       "replicas still updating",
+			deployment: deployment(2, 2, 3, 1, 3, 4),
+			want:       false,
+		},
+		{
+			name:       "old replicas still terminating",
+			deployment: deployment(2, 2, 3, 3, 3, 4),
+			want:       false,
+		},
+		{
+			name:       "scaled down to zero",
+			deployment: deployment(2, 2, 0, 0, 0, 0),
+			want:       true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rolledOut(tt.deployment); got != tt.want {
+				t.Errorf("rolledOut() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestObserveStatus(t *testing.T) {
+	key := types.NamespacedName{Namespace: "acme", Name: "observe-status"}
+	status := &acmeiov1beta1.ApplicationStatus{
+		Reason:   "completed",
+		Children: []acmeiov1beta1.ApplicationChildStatus{{Kind: "Deployment", Synced: true}, {Kind: "Service", Synced: false}},
+		Conditions: []metav1.Condition{
+			{Type: acmeiov1beta1.ConditionSuspended, Status: metav1.ConditionTrue},
+		},
+	}
+
+	observeStatus(key, status)
+	if got := testutil.ToFloat64(applicationReady.WithLabelValues(key.Namespace, key.Name)); got != 0 {
+		t.Errorf("acme_application_ready = %v, want 0 with a child out of sync", got)
+	}
+	if got := testutil.ToFloat64(applicationCondition.WithLabelValues(key.Namespace, key.Name, acmeiov1beta1.ConditionSuspended, "True")); got != 1 {
+		t.Errorf("acme_application_status_condition{status=True} = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(applicationCondition.WithLabelValues(key.Namespace, key.Name, acmeiov1beta1.ConditionSuspended, "False")); got != 0 {
+		t.Errorf("acme_application_status_condition{status=False} = %v, want 0", got)
+	}
+
+	status.Children[1].Synced = true
+	observeStatus(key, status)
+	if got := testutil.ToFloat64(applicationReady.WithLabelValues(key.Namespace, key.Name)); got != 1 {
+		t.Errorf("acme_application_ready = %v, want 1", got)
+	}
+
+	(&ApplicationReconciler{}).forgetMetrics(key)
+	if got := testutil.CollectAndCount(applicationCondition); got != 0 {
+		t.Errorf("acme_application_status_condition series = %v, want 0 once forgotten", got)
+	}
+}
+
+func TestReconcile_ReadyIsKept(t *testing.T) {
+	cr := reconcilingCR("ready-is-kept")
+	cluster := newFakeCluster(t, cr)
+	if _, err := cluster.reconcile(t, cr); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	ready := applicationReady.WithLabelValues(cr.GetNamespace(), cr.GetName())
+	if got := testutil.ToFloat64(ready); got != 1 {
+		t.Fatalf("acme_application_ready = %v, want 1", got)
+	}
+
+	// The readiness is sampled on every status write of the next reconciliation,
+	// including the progressing status written before the children are reconciled
+	samples := []float64{}
+	cluster.reconciler.Client = interceptor.NewClient(cluster.reconciler.Client.(client.WithWatch), interceptor.Funcs{
+		SubResourceUpdate: func(ctx context.Context, clnt client.Client, subResourceName string, obj client.Object, opts ...client.SubResourceUpdateOption) error {
+			samples = append(samples, testutil.ToFloat64(ready))
+			return clnt.SubResource(subResourceName).Update(ctx, obj, opts...)
+		},
+	})
+	if _, err := cluster.reconcile(t, cr); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	if len(samples) == 0 {
+		t.Fatalf("no status was written, want the progressing status written")
+	}
+	for i, got := range samples {
+		if got != 1 {
+			t.Errorf("acme_application_ready = %v at status write %d, want 1 throughout a healthy reconciliation", got, i)
+		}
+	}
+}
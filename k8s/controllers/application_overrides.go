@@ -0,0 +1,56 @@
+/*
+Copyright 2023 Nathan Brophy.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+
+	acmeiov1beta1 "github.com/nathanbrophy/portfolio-demo/k8s/api/v1beta1"
+	acmeoverrides "github.com/nathanbrophy/portfolio-demo/k8s/overrides"
+)
+
+// overriding applies the overrides of the CR, in order, to the generated manifests of their kind.  The manifests are
+// patched before anything else reads them, so that the patched manifest is what is applied, hashed and compared for
+// drift.  An override for a kind that is not generated for the CR is an error, as it would otherwise be silently lost.
+func overriding(toReconcile []ReconcileWrapper, cr *acmeiov1beta1.Application) ([]ReconcileWrapper, error) {
+	for i, override := range cr.Spec.Overrides {
+		patch, err := acmeoverrides.Parse(string(override.Type), override.Patch)
+		if err != nil {
+			return nil, fmt.Errorf("override %d for %s: %w", i, override.Kind, err)
+		}
+
+		found := false
+		for j := range toReconcile {
+			gvk := toReconcile[j].Manifest.GetObjectKind().GroupVersionKind()
+			if gvk.Kind != override.Kind {
+				continue
+			}
+
+			patched, err := patch.Apply(toReconcile[j].Manifest)
+			if err != nil {
+				return nil, fmt.Errorf("override %d for %s: %w", i, override.Kind, err)
+			}
+			toReconcile[j].Manifest = patched
+			found = true
+		}
+		if !found {
+			return nil, fmt.Errorf("override %d is for %s, which is not generated for the Application", i, override.Kind)
+		}
+	}
+
+	return toReconcile, nil
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2023 Nathan Brophy.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+
+	acmeiov1beta1 "github.com/nathanbrophy/portfolio-demo/k8s/api/v1beta1"
+	acmegdrift "github.com/nathanbrophy/portfolio-demo/k8s/driftDetection"
+	acmegenerators "github.com/nathanbrophy/portfolio-demo/k8s/generators"
+	acmetest "github.com/nathanbrophy/portfolio-demo/k8s/utils/test"
+)
+
+func TestOverriding(t *testing.T) {
+	tests := []struct {
+		name         string
+		overrides    []acmeiov1beta1.ApplicationOverride
+		wantPriority string
+		wantPolicy   corev1.ServiceExternalTrafficPolicyType
+		wantChanged  map[string]bool
+		wantErr      bool
+	}{
+		{
+			name: "no overrides",
+		},
+		{
+			name: "overrides of several kinds",
+			overrides: []acmeiov1beta1.ApplicationOverride{
+				{
+					Kind:  "Deployment",
+					Patch: "spec:\n  template:\n    spec:\n      priorityClassName: low\n",
+				},
+				{
+					Kind:  "Service",
+					Type:  acmeiov1beta1.OverridePatchTypeJSON6902,
+					Patch: `[{"op": "add", "path": "/spec/externalTrafficPolicy", "value": "Local"}]`,
+				},
+				{
+					Kind:  "Deployment",
+					Patch: `{"spec": {"template": {"spec": {"priorityClassName": "critical"}}}}`,
+				},
+			},
+			wantPriority: "critical",
+			wantPolicy:   corev1.ServiceExternalTrafficPolicyTypeLocal,
+			wantChanged:  map[string]bool{"Deployment": true, "Service": true},
+		},
+		{
+			name: "kind that is not generated",
+			overrides: []acmeiov1beta1.ApplicationOverride{
+				{Kind: "StatefulSet", Patch: `{"spec": {}}`},
+			},
+			wantErr: true,
+		},
+		{
+			name: "patch that does not apply",
+			overrides: []acmeiov1beta1.ApplicationOverride{
+				{
+					Kind:  "Deployment",
+					Type:  acmeiov1beta1.OverridePatchTypeJSON6902,
+					Patch: `[{"op": "remove", "path": "/spec/template/spec/hostname"}]`,
+				},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cr := acmetest.GenerateCRWithDefaults().(*acmeiov1beta1.Application)
+			cr.Spec.Overrides = tt.overrides
+
+			generated := manifests(acmegenerators.DefaultRegistry, cr)
+			toReconcile, err := overriding(manifests(acmegenerators.DefaultRegistry, cr), cr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("overriding() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			for i, reconcilers := range toReconcile {
+				switch manifest := reconcilers.Manifest.(type) {
+				case *appsv1.Deployment:
+					if got := manifest.Spec.Template.Spec.PriorityClassName; got != tt.wantPriority {
+						t.Errorf("Deployment priorityClassName = %q, want %q", got, tt.wantPriority)
+					}
+				case *corev1.Service:
+					if got := manifest.Spec.ExternalTrafficPolicy; got != tt.wantPolicy {
+						t.Errorf("Service externalTrafficPolicy = %q, want %q", got, tt.wantPolicy)
+					}
+				}
+
+				// The overrides are part of the manifest, so an object that was
+				// applied without them is applied again
+				applied := generated[i].Manifest
+				if err := acmegdrift.Stamp(applied); err != nil {
+					t.Fatalf("Stamp() error = %v", err)
+				}
+				report, err := reconcilers.Driftor(reconcilers.Manifest, applied)
+				if err != nil {
+					t.Fatalf("%T drift detection error = %v", reconcilers.Manifest, err)
+				}
+				kind := reconcilers.Manifest.GetObjectKind().GroupVersionKind().Kind
+				if report.Changed != tt.wantChanged[kind] || report.Drifted() {
+					t.Errorf("%s changed = %v, drifted = %v, want a change %v and no drift: %s", kind, report.Changed, report.Drifted(), tt.wantChanged[kind], report)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+/*
+Copyright 2023 Nathan Brophy.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	acmeiov1beta1 "github.com/nathanbrophy/portfolio-demo/k8s/api/v1beta1"
+)
+
+// AdoptAnnotation opts an existing object, that no controller owns, into being adopted by the Application it names
+const AdoptAnnotation string = "acme.io/adopt"
+
+// mayManage reports if the CR may manage an object found on the cluster.  Objects the CR controls are managed,
+// objects without a controller are only adopted when they are annotated with the name of the CR, and objects
+// controlled by anything else are never touched.
+func mayManage(cr, found client.Object) bool {
+	if metav1.IsControlledBy(found, cr) {
+		return true
+	}
+	if metav1.GetControllerOf(found) != nil {
+		return false
+	}
+
+	annotations := found.GetAnnotations()
+	return annotations[AdoptAnnotation] == cr.GetName() || annotations[RetainedByAnnotation] == cr.GetName()
+}
+
+// ownershipCondition builds the OwnershipConflict condition for the CR from the objects it was not allowed to manage
+func ownershipCondition(cr *acmeiov1beta1.Application, conflicts []string) metav1.Condition {
+	condition := metav1.Condition{
+		Type:               acmeiov1beta1.ConditionOwnershipConflict,
+		Status:             metav1.ConditionFalse,
+		Reason:             "AllObjectsControlled",
+		Message:            "every downstream object is controlled by the Application",
+		ObservedGeneration: cr.GetGeneration(),
+	}
+	if len(conflicts) > 0 {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = "OwnershipConflict"
+		condition.Message = "objects exist that the Application does not control, annotate them with " +
+			AdoptAnnotation + "=" + cr.GetName() + " to adopt them: " + strings.Join(conflicts, ", ")
+	}
+
+	return condition
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2023 Nathan Brophy.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	acmeiov1beta1 "github.com/nathanbrophy/portfolio-demo/k8s/api/v1beta1"
+)
+
+func TestMayManage(t *testing.T) {
+	isController := true
+	cr := &acmeiov1beta1.Application{
+		ObjectMeta: metav1.ObjectMeta{Name: "example", Namespace: "example-ns", UID: types.UID("application-uid")},
+	}
+	owner := func(uid types.UID) []metav1.OwnerReference {
+		return []metav1.OwnerReference{{Name: "example", UID: uid, Controller: &isController}}
+	}
+
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		owners      []metav1.OwnerReference
+		want        bool
+	}{
+		{
+			name:   "controlled by the application",
+			owners: owner("application-uid"),
+			want:   true,
+		},
+		{
+			name: "unowned",
+			want: false,
+		},
+		{
+			name:        "unowned with the adopt annotation",
+			annotations: map[string]string{AdoptAnnotation: "example"},
+			want:        true,
+		},
+		{
+			name:        "adopt annotation naming another application",
+			annotations: map[string]string{AdoptAnnotation: "other"},
+			want:        false,
+		},
+		{
+			name:        "retained from an application of the same name",
+			annotations: map[string]string{RetainedByAnnotation: "example"},
+			want:        true,
+		},
+		{
+			name:        "controlled by something else",
+			annotations: map[string]string{AdoptAnnotation: "example"},
+			owners:      owner("other-uid"),
+			want:        false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			found := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            "acme-application",
+					Namespace:       "example-ns",
+					Annotations:     tt.annotations,
+					OwnerReferences: tt.owners,
+				},
+			}
+			if got := mayManage(cr, found); got != tt.want {
+				t.Errorf("mayManage() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,460 @@
+/*
+Copyright 2023 Nathan Brophy.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	acmeiov1beta1 "github.com/nathanbrophy/portfolio-demo/k8s/api/v1beta1"
+	acmeoperatorconfig "github.com/nathanbrophy/portfolio-demo/k8s/operatorconfig"
+	acmepolicy "github.com/nathanbrophy/portfolio-demo/k8s/policy"
+)
+
+// fakeCluster runs the reconciler against a fake client.  The fake client has no server side apply, so an apply
+// of a missing object creates it instead, and the options of every apply are recorded to check on the field manager.
+type fakeCluster struct {
+	reconciler *ApplicationReconciler
+	recorder   *record.FakeRecorder
+
+	// applies holds the options of the last apply of every object, by kind and name
+	applies map[string]*client.PatchOptions
+
+	// failures makes the apply of an object fail, by kind and name
+	failures map[string]error
+}
+
+func newFakeCluster(t *testing.T, objs ...client.Object) *fakeCluster {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme() error = %v", err)
+	}
+	if err := acmeiov1beta1.AddToScheme(scheme); err != nil {
+		t.Fatalf("AddToScheme() error = %v", err)
+	}
+
+	cluster := &fakeCluster{
+		recorder: record.NewFakeRecorder(100),
+		applies:  map[string]*client.PatchOptions{},
+		failures: map[string]error{},
+	}
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(objs...).
+		WithStatusSubresource(&acmeiov1beta1.Application{}).
+		WithInterceptorFuncs(interceptor.Funcs{Patch: cluster.patch}).
+		Build()
+	cluster.reconciler = &ApplicationReconciler{
+		Client:   fakeClient,
+		Scheme:   scheme,
+		Recorder: cluster.recorder,
+	}
+
+	return cluster
+}
+
+func (c *fakeCluster) patch(ctx context.Context, clnt client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if patch.Type() != types.ApplyPatchType {
+		return clnt.Patch(ctx, obj, patch, opts...)
+	}
+
+	key := objectKey(obj.GetObjectKind().GroupVersionKind().Kind, obj.GetName())
+	if err := c.failures[key]; err != nil {
+		return err
+	}
+	options := &client.PatchOptions{}
+	options.ApplyOptions(opts)
+	c.applies[key] = options
+
+	err := clnt.Patch(ctx, obj, patch, opts...)
+	if errors.IsNotFound(err) {
+		return clnt.Create(ctx, obj)
+	}
+
+	return err
+}
+
+// reconcile runs a single reconciliation of the CR, the metrics of the CR are dropped once the test is done
+func (c *fakeCluster) reconcile(t *testing.T, cr *acmeiov1beta1.Application) (ctrl.Result, error) {
+	t.Helper()
+
+	key := client.ObjectKeyFromObject(cr)
+	t.Cleanup(func() { c.reconciler.forgetMetrics(key) })
+
+	return c.reconciler.Reconcile(context.Background(), ctrl.Request{NamespacedName: key})
+}
+
+// application reads the CR back from the cluster
+func (c *fakeCluster) application(t *testing.T, cr *acmeiov1beta1.Application) *acmeiov1beta1.Application {
+	t.Helper()
+
+	found := &acmeiov1beta1.Application{}
+	if err := c.reconciler.Client.Get(context.Background(), client.ObjectKeyFromObject(cr), found); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	return found
+}
+
+// update changes the spec of the CR on the cluster, as a user editing it would
+func (c *fakeCluster) update(t *testing.T, cr *acmeiov1beta1.Application, mutate func(*acmeiov1beta1.Application)) {
+	t.Helper()
+
+	found := c.application(t, cr)
+	mutate(found)
+	found.SetGeneration(found.GetGeneration() + 1)
+	if err := c.reconciler.Client.Update(context.Background(), found); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+}
+
+// events drains the events recorded so far
+func (c *fakeCluster) events() []string {
+	events := []string{}
+	for {
+		select {
+		case event := <-c.recorder.Events:
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
+
+func objectKey(kind, name string) string {
+	return kind + "/" + name
+}
+
+// reconcilingCR is a CR with its image set and every other field defaulted
+func reconcilingCR(name string) *acmeiov1beta1.Application {
+	cr := &acmeiov1beta1.Application{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "apps", UID: types.UID(name + "-uid"), Generation: 1},
+	}
+	cr.Spec.Application = &acmeiov1beta1.ApplicationApplication{
+		Image: func(x string) *string { return &x }("example.com/test-image:v1.0"),
+	}
+
+	return cr
+}
+
+func TestReconcile_ManifestChange(t *testing.T) {
+	for _, policy := range []acmeiov1beta1.DriftPolicy{acmeiov1beta1.DriftPolicyCorrect, acmeiov1beta1.DriftPolicyReport, acmeiov1beta1.DriftPolicyIgnore} {
+		t.Run(string(policy), func(t *testing.T) {
+			cr := reconcilingCR("manifest-change-" + strings.ToLower(string(policy)))
+			cr.Spec.DriftPolicy = &acmeiov1beta1.ApplicationDriftPolicy{Default: policy}
+			cluster := newFakeCluster(t, cr)
+			if _, err := cluster.reconcile(t, cr); err != nil {
+				t.Fatalf("Reconcile() error = %v", err)
+			}
+			cluster.events()
+
+			cluster.update(t, cr, func(found *acmeiov1beta1.Application) {
+				found.Spec.Application.Image = func(x string) *string { return &x }("example.com/test-image:v2.0")
+			})
+			detections := testutil.ToFloat64(driftDetectionsTotal.WithLabelValues(cr.GetNamespace(), cr.GetName(), "Deployment"))
+			if _, err := cluster.reconcile(t, cr); err != nil {
+				t.Fatalf("Reconcile() error = %v", err)
+			}
+
+			// The change to the Application is applied whatever the drift policy
+			deployment := &appsv1.Deployment{}
+			if err := cluster.reconciler.Client.Get(context.Background(), client.ObjectKey{Namespace: cr.GetNamespace(), Name: acmeiov1beta1.NAME}, deployment); err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			if got := deployment.Spec.Template.Spec.Containers[0].Image; got != "example.com/test-image:v2.0" {
+				t.Errorf("Deployment image = %v, want the image the Application changed to", got)
+			}
+
+			// and is not reported as drift
+			found := cluster.application(t, cr)
+			if len(found.Status.RecentDrift) != 0 {
+				t.Errorf("status.recentDrift = %v, want no drift recorded for a change to the Application", found.Status.RecentDrift)
+			}
+			for _, child := range found.Status.Children {
+				if child.Kind == "Deployment" && child.Reason != childReasonUpdated {
+					t.Errorf("Deployment child reason = %v, want %v", child.Reason, childReasonUpdated)
+				}
+			}
+			for _, event := range cluster.events() {
+				if strings.Contains(event, "DriftDetected") {
+					t.Errorf("event %q, want no drift event for a change to the Application", event)
+				}
+			}
+			if got := testutil.ToFloat64(driftDetectionsTotal.WithLabelValues(cr.GetNamespace(), cr.GetName(), "Deployment")); got != detections {
+				t.Errorf("drift detections = %v, want %v", got, detections)
+			}
+		})
+	}
+}
+
+func TestReconcile_Drift(t *testing.T) {
+	tests := []struct {
+		policy    acmeiov1beta1.DriftPolicy
+		wantImage string
+		wantDrift bool
+	}{
+		{
+			policy:    acmeiov1beta1.DriftPolicyCorrect,
+			wantImage: "example.com/test-image:v1.0",
+			wantDrift: true,
+		},
+		{
+			policy:    acmeiov1beta1.DriftPolicyReport,
+			wantImage: "example.com/test-image:hotfix",
+			wantDrift: true,
+		},
+		{
+			policy:    acmeiov1beta1.DriftPolicyIgnore,
+			wantImage: "example.com/test-image:hotfix",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			cr := reconcilingCR("drift-" + strings.ToLower(string(tt.policy)))
+			cr.Spec.DriftPolicy = &acmeiov1beta1.ApplicationDriftPolicy{Default: tt.policy}
+			cluster := newFakeCluster(t, cr)
+			if _, err := cluster.reconcile(t, cr); err != nil {
+				t.Fatalf("Reconcile() error = %v", err)
+			}
+			cluster.events()
+
+			// The Deployment is edited on the cluster, without a change to the Application
+			key := client.ObjectKey{Namespace: cr.GetNamespace(), Name: acmeiov1beta1.NAME}
+			deployment := &appsv1.Deployment{}
+			if err := cluster.reconciler.Client.Get(context.Background(), key, deployment); err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			deployment.Spec.Template.Spec.Containers[0].Image = "example.com/test-image:hotfix"
+			if err := cluster.reconciler.Client.Update(context.Background(), deployment); err != nil {
+				t.Fatalf("Update() error = %v", err)
+			}
+			if _, err := cluster.reconcile(t, cr); err != nil {
+				t.Fatalf("Reconcile() error = %v", err)
+			}
+
+			if err := cluster.reconciler.Client.Get(context.Background(), key, deployment); err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			if got := deployment.Spec.Template.Spec.Containers[0].Image; got != tt.wantImage {
+				t.Errorf("Deployment image = %v, want %v", got, tt.wantImage)
+			}
+
+			found := cluster.application(t, cr)
+			if got := len(found.Status.RecentDrift) > 0; got != tt.wantDrift {
+				t.Errorf("status.recentDrift = %v, want drift recorded %v", found.Status.RecentDrift, tt.wantDrift)
+			}
+			drifted := false
+			for _, event := range cluster.events() {
+				drifted = drifted || strings.Contains(event, "DriftDetected")
+			}
+			if drifted != tt.wantDrift {
+				t.Errorf("DriftDetected event = %v, want %v", drifted, tt.wantDrift)
+			}
+		})
+	}
+}
+
+func TestReconcile_PermanentErrorIsResynced(t *testing.T) {
+	cr := reconcilingCR("image-policy")
+	cluster := newFakeCluster(t, cr)
+	cluster.reconciler.ResyncPeriod = time.Minute
+	cluster.reconciler.Config = acmeoperatorconfig.NewStore(nil)
+	cluster.reconciler.Config.Set(&acmeoperatorconfig.Config{
+		ImagePolicy: acmepolicy.ImagePolicy{AllowedRegistries: []string{"quay.io/acme"}},
+	})
+
+	result, err := cluster.reconcile(t, cr)
+	if err != nil || result.RequeueAfter != time.Minute {
+		t.Fatalf("Reconcile() = %v, %v, want the image policy violation checked on again at the resync period", result, err)
+	}
+	if found := cluster.application(t, cr); !strings.Contains(found.Status.Reason, "image policy") {
+		t.Errorf("status.reason = %q, want the image policy violation", found.Status.Reason)
+	}
+
+	// The policy is relaxed by reloading the operator config, which raises no
+	// event, and is picked up by the resync
+	cluster.reconciler.Config.Set(&acmeoperatorconfig.Config{})
+	if _, err := cluster.reconcile(t, cr); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	deployment := &appsv1.Deployment{}
+	if err := cluster.reconciler.Client.Get(context.Background(), client.ObjectKey{Namespace: cr.GetNamespace(), Name: acmeiov1beta1.NAME}, deployment); err != nil {
+		t.Errorf("Get() error = %v, want the Deployment created once the policy allows its image", err)
+	}
+}
+
+func TestReconcile_EveryChild(t *testing.T) {
+	cr := reconcilingCR("every-child")
+	cluster := newFakeCluster(t, cr)
+	if _, err := cluster.reconcile(t, cr); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+
+	// The Deployment, reconciled first, is in sync, which does not keep the
+	// Service deleted out of band from being created again
+	service := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: cr.GetNamespace(), Name: acmeiov1beta1.NAME}}
+	if err := cluster.reconciler.Client.Delete(context.Background(), service); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := cluster.reconcile(t, cr); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	if err := cluster.reconciler.Client.Get(context.Background(), client.ObjectKeyFromObject(service), service); err != nil {
+		t.Errorf("Get() error = %v, want the Service created again after an in sync Deployment", err)
+	}
+	reasons := map[string]string{}
+	for _, child := range cluster.application(t, cr).Status.Children {
+		reasons[child.Kind] = child.Reason
+	}
+	if reasons["Deployment"] != childReasonInSync || reasons["Service"] != childReasonCreated {
+		t.Errorf("status.children reasons = %v, want the Deployment in sync and the Service created", reasons)
+	}
+}
+
+func TestReconcile_ChildErrors(t *testing.T) {
+	cr := reconcilingCR("child-errors")
+	cluster := newFakeCluster(t, cr)
+	cluster.failures[objectKey("Deployment", acmeiov1beta1.NAME)] = fmt.Errorf("the Deployment webhook is down")
+	cluster.failures[objectKey("ServiceAccount", acmeiov1beta1.SERVICE_ACCOUNT)] = fmt.Errorf("the ServiceAccount quota is exceeded")
+
+	_, err := cluster.reconcile(t, cr)
+	if err == nil {
+		t.Fatalf("Reconcile() error = nil, want the errors of the failed children")
+	}
+	for _, want := range []string{"the Deployment webhook is down", "the ServiceAccount quota is exceeded"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Reconcile() error = %v, want it to contain %q", err, want)
+		}
+	}
+
+	// Every failure is reported on its own child, and the children that did not
+	// fail are still reconciled
+	found := cluster.application(t, cr)
+	children := map[string]acmeiov1beta1.ApplicationChildStatus{}
+	for _, child := range found.Status.Children {
+		children[child.Kind] = child
+	}
+	tests := []struct {
+		kind        string
+		wantReason  string
+		wantMessage string
+	}{
+		{kind: "Deployment", wantReason: childReasonFailed, wantMessage: "the Deployment webhook is down"},
+		{kind: "ServiceAccount", wantReason: childReasonFailed, wantMessage: "the ServiceAccount quota is exceeded"},
+		{kind: "Service", wantReason: childReasonCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.kind, func(t *testing.T) {
+			child, ok := children[tt.kind]
+			if !ok {
+				t.Fatalf("status.children = %v, want a %s", found.Status.Children, tt.kind)
+			}
+			if child.Reason != tt.wantReason || child.Message != tt.wantMessage || child.Synced != (tt.wantReason != childReasonFailed) {
+				t.Errorf("%s child = %+v, want reason %q with message %q", tt.kind, child, tt.wantReason, tt.wantMessage)
+			}
+		})
+	}
+	if !strings.Contains(found.Status.Reason, "the Deployment webhook is down") || !strings.Contains(found.Status.Reason, "the ServiceAccount quota is exceeded") {
+		t.Errorf("status.reason = %q, want both failures", found.Status.Reason)
+	}
+}
+
+func TestReconcile_ServerSideApply(t *testing.T) {
+	cr := reconcilingCR("server-side-apply")
+	cluster := newFakeCluster(t, cr)
+	if _, err := cluster.reconcile(t, cr); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+
+	if len(cluster.applies) == 0 {
+		t.Fatalf("Reconcile() applied nothing, want every child applied server side")
+	}
+	for key, options := range cluster.applies {
+		if options.FieldManager != FieldOwner || options.Force == nil || !*options.Force {
+			t.Errorf("%s applied with field manager %q and force %v, want %q forcing ownership", key, options.FieldManager, options.Force, FieldOwner)
+		}
+	}
+}
+
+func TestReconcile_AutoscaledReplicas(t *testing.T) {
+	cr := reconcilingCR("autoscaled")
+	cluster := newFakeCluster(t, cr)
+	if _, err := cluster.reconcile(t, cr); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+
+	// An autoscaler scales the Deployment of an Application that sets no replicas
+	key := client.ObjectKey{Namespace: cr.GetNamespace(), Name: acmeiov1beta1.NAME}
+	deployment := &appsv1.Deployment{}
+	if err := cluster.reconciler.Client.Get(context.Background(), key, deployment); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if deployment.Spec.Replicas != nil {
+		t.Errorf("Deployment replicas = %v, want them left out of the manifest", *deployment.Spec.Replicas)
+	}
+	deployment.Spec.Replicas = func(x int32) *int32 { return &x }(5)
+	if err := cluster.reconciler.Client.Update(context.Background(), deployment); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	cluster.events()
+
+	if _, err := cluster.reconcile(t, cr); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	if err := cluster.reconciler.Client.Get(context.Background(), key, deployment); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got := *deployment.Spec.Replicas; got != 5 {
+		t.Errorf("Deployment replicas = %v, want the 5 replicas of the autoscaler kept", got)
+	}
+	for _, event := range cluster.events() {
+		if strings.Contains(event, "DriftDetected") {
+			t.Errorf("event %q, want the replicas of the autoscaler not reported as drift", event)
+		}
+	}
+
+	// A hibernation window still scales the Deployment down to zero
+	cluster.update(t, cr, func(found *acmeiov1beta1.Application) {
+		found.Spec.Hibernation = &acmeiov1beta1.ApplicationHibernation{Sleep: "0 0 1 1 *", Wake: "* * * * *"}
+	})
+	if _, err := cluster.reconcile(t, cr); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	if err := cluster.reconciler.Client.Get(context.Background(), key, deployment); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got := *deployment.Spec.Replicas; got != 0 || !cluster.application(t, cr).Status.Hibernating {
+		t.Errorf("Deployment replicas = %v, want the hibernating Application scaled down to zero", got)
+	}
+}
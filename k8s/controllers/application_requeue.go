@@ -0,0 +1,120 @@
+/*
+Copyright 2023 Nathan Brophy.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"errors"
+	"time"
+
+	"golang.org/x/time/rate"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/util/workqueue"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/ratelimiter"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// Backoff defines how long the controller waits before retrying a failed reconciliation of an Application.  The wait
+// starts at the base delay and doubles on every consecutive failure of the same Application, up to the max delay,
+// and is reset as soon as a reconciliation of that Application succeeds.
+type Backoff struct {
+	// BaseDelay is the wait before the first retry
+	BaseDelay time.Duration
+
+	// MaxDelay is the longest wait between two retries
+	MaxDelay time.Duration
+}
+
+// RateLimiter builds the rate limiter of the controller work queue for the backoff.  The overall limit of the
+// default controller rate limiter is kept, so that failures across many Applications cannot flood the API server.
+// The default controller rate limiter is used as is when no base delay is set.
+func (b Backoff) RateLimiter() ratelimiter.RateLimiter {
+	if b.BaseDelay <= 0 {
+		return workqueue.DefaultControllerRateLimiter()
+	}
+
+	return workqueue.NewMaxOfRateLimiter(
+		workqueue.NewItemExponentialFailureRateLimiter(b.BaseDelay, b.MaxDelay),
+		&workqueue.BucketRateLimiter{Limiter: rate.NewLimiter(rate.Limit(10), 100)},
+	)
+}
+
+// permanentError marks an error that retrying cannot fix, only a change to the CR can
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// permanent marks an error as one that is not worth retrying
+func permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &permanentError{err: err}
+}
+
+// isPermanent reports if retrying cannot fix an error.  Besides the errors marked as permanent, a request the API
+// server rejects as invalid is permanent, as the same manifest is generated until the CR changes.  An aggregate is
+// only permanent when all of its errors are.
+func isPermanent(err error) bool {
+	var aggregate utilerrors.Aggregate
+	if errors.As(err, &aggregate) {
+		for _, err := range aggregate.Errors() {
+			if !isPermanent(err) {
+				return false
+			}
+		}
+		return len(aggregate.Errors()) > 0
+	}
+
+	var marked *permanentError
+	return errors.As(err, &marked) || apierrors.IsInvalid(err) || apierrors.IsBadRequest(err)
+}
+
+// requeue is the result of a reconciliation that failed with the given error.  Transient errors are handed to the
+// controller, which retries them with the backoff of its rate limiter.  Permanent errors are still reported, but
+// never retried, as the change to the CR that fixes them triggers a reconciliation of its own.
+func requeue(err error) (ctrl.Result, error) {
+	if err == nil {
+		return ctrl.Result{}, nil
+	}
+	if isPermanent(err) {
+		return ctrl.Result{}, reconcile.TerminalError(err)
+	}
+
+	return ctrl.Result{}, err
+}
+
+// resyncPermanent reports a permanent failure as a reconciliation to check back on at the resync period.  The result
+// of a reconciliation that returns an error is dropped by the controller, which would otherwise leave the CR alone
+// until it changes, even though a change that raises no event, such as a reloaded operator config, may fix it too.
+func resyncPermanent(result ctrl.Result, err error, period time.Duration) (ctrl.Result, error) {
+	if period <= 0 || !errors.Is(err, reconcile.TerminalError(nil)) {
+		return result, err
+	}
+
+	return requeueWithin(result, period), nil
+}
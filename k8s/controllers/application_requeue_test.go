@@ -0,0 +1,161 @@
+/*
+Copyright 2023 Nathan Brophy.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestRequeue(t *testing.T) {
+	conflict := apierrors.NewConflict(schema.GroupResource{Group: "acme.io", Resource: "applications"}, "application-sample", fmt.Errorf("the object has been modified"))
+	invalid := apierrors.NewInvalid(schema.GroupKind{Group: "apps", Kind: "Deployment"}, "application-sample", field.ErrorList{field.Invalid(field.NewPath("spec", "replicas"), -1, "must be positive")})
+
+	tests := []struct {
+		name         string
+		err          error
+		wantErr      bool
+		wantTerminal bool
+	}{
+		{
+			name: "no error",
+		},
+		{
+			name:    "transient",
+			err:     conflict,
+			wantErr: true,
+		},
+		{
+			name:         "marked permanent",
+			err:          permanent(fmt.Errorf("revision 3 was not found in the revision history")),
+			wantErr:      true,
+			wantTerminal: true,
+		},
+		{
+			name:         "rejected as invalid",
+			err:          fmt.Errorf("Deployment application-sample: %w", invalid),
+			wantErr:      true,
+			wantTerminal: true,
+		},
+		{
+			name:         "every error permanent",
+			err:          utilerrors.NewAggregate([]error{invalid, permanent(fmt.Errorf("bad window"))}),
+			wantErr:      true,
+			wantTerminal: true,
+		},
+		{
+			name:    "some errors transient",
+			err:     utilerrors.NewAggregate([]error{invalid, conflict}),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := requeue(tt.err)
+			if !result.IsZero() {
+				t.Errorf("requeue() result = %v, want the requeue left to the rate limiter", result)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("requeue() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := errors.Is(err, reconcile.TerminalError(nil)); got != tt.wantTerminal {
+				t.Errorf("requeue() terminal = %v, want %v", got, tt.wantTerminal)
+			}
+		})
+	}
+}
+
+func TestResyncPermanent(t *testing.T) {
+	tests := []struct {
+		name      string
+		result    ctrl.Result
+		err       error
+		period    time.Duration
+		wantAfter time.Duration
+		wantErr   bool
+	}{
+		{
+			name:      "success",
+			period:    time.Minute,
+			wantAfter: 0,
+		},
+		{
+			name:    "transient error",
+			err:     fmt.Errorf("the registry is unreachable"),
+			period:  time.Minute,
+			wantErr: true,
+		},
+		{
+			name:      "permanent error",
+			err:       reconcile.TerminalError(fmt.Errorf("the image breaks the image policy")),
+			period:    time.Minute,
+			wantAfter: time.Minute,
+		},
+		{
+			name:      "permanent error inside a hibernation window",
+			result:    ctrl.Result{RequeueAfter: time.Second * 30},
+			err:       reconcile.TerminalError(fmt.Errorf("the image breaks the image policy")),
+			period:    time.Minute,
+			wantAfter: time.Second * 30,
+		},
+		{
+			name:    "permanent error without a resync period",
+			err:     reconcile.TerminalError(fmt.Errorf("the image breaks the image policy")),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := resyncPermanent(tt.result, tt.err, tt.period)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resyncPermanent() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if result.RequeueAfter != tt.wantAfter {
+				t.Errorf("resyncPermanent() requeue after = %v, want %v", result.RequeueAfter, tt.wantAfter)
+			}
+		})
+	}
+}
+
+func TestBackoff_RateLimiter(t *testing.T) {
+	limiter := Backoff{BaseDelay: 5 * time.Second, MaxDelay: 30 * time.Second}.RateLimiter()
+
+	want := []time.Duration{5 * time.Second, 10 * time.Second, 20 * time.Second, 30 * time.Second, 30 * time.Second}
+	for i, delay := range want {
+		if got := limiter.When("application-sample"); got != delay {
+			t.Errorf("When() after %d failures = %v, want %v", i, got, delay)
+		}
+	}
+
+	if got := limiter.When("another-application"); got != 5*time.Second {
+		t.Errorf("When() for another Application = %v, want %v", got, 5*time.Second)
+	}
+
+	limiter.Forget("application-sample")
+	if got := limiter.When("application-sample"); got != 5*time.Second {
+		t.Errorf("When() after a success = %v, want %v", got, 5*time.Second)
+	}
+}
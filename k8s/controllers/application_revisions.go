@@ -0,0 +1,182 @@
+/*
+Copyright 2023 Nathan Brophy.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	acmeiov1beta1 "github.com/nathanbrophy/portfolio-demo/k8s/api/v1beta1"
+	acmerevisions "github.com/nathanbrophy/portfolio-demo/k8s/revisions"
+)
+
+// withRevision records the revision being reconciled in the status of the CR
+func withRevision(rev *appsv1.ControllerRevision) statusMutator {
+	return func(status *acmeiov1beta1.ApplicationStatus) {
+		status.CurrentRevision = rev.Name
+		status.Revision = rev.Revision
+	}
+}
+
+// history loads the revisions controlled by the CR, ordered from the oldest to the newest
+func (r *ApplicationReconciler) history(ctx context.Context, cr *acmeiov1beta1.Application) ([]appsv1.ControllerRevision, error) {
+	found := &appsv1.ControllerRevisionList{}
+	if err := r.Client.List(
+		ctx,
+		found,
+		client.InNamespace(cr.Namespace),
+		client.MatchingLabels{acmerevisions.ApplicationLabel: cr.GetName()},
+	); err != nil {
+		return nil, err
+	}
+
+	// The label alone is not proof of ownership, a previous CR with the same
+	// name may have left revisions behind that are pending garbage collection.
+	owned := []appsv1.ControllerRevision{}
+	for i := range found.Items {
+		if metav1.IsControlledBy(&found.Items[i], cr) {
+			owned = append(owned, found.Items[i])
+		}
+	}
+	acmerevisions.Sort(owned)
+
+	return owned, nil
+}
+
+// recordRevision ensures the current spec of the CR is the newest revision in its history, and
+// prunes the revisions that exceed the revision history limit
+func (r *ApplicationReconciler) recordRevision(ctx context.Context, cr *acmeiov1beta1.Application) (*appsv1.ControllerRevision, error) {
+	history, err := r.history(ctx, cr)
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := acmerevisions.Hash(&cr.Spec)
+	if err != nil {
+		return nil, err
+	}
+
+	next := int64(1)
+	if len(history) > 0 {
+		next = history[len(history)-1].Revision + 1
+	}
+
+	var current *appsv1.ControllerRevision
+	for i := range history {
+		if history[i].Labels[acmerevisions.HashLabel] == hash {
+			current = &history[i]
+		}
+	}
+
+	switch {
+	case current == nil:
+		current, err = acmerevisions.New(cr, next)
+		if err != nil {
+			return nil, err
+		}
+		if err := ctrl.SetControllerReference(cr, current, r.Scheme); err != nil {
+			return nil, err
+		}
+		if err := r.Client.Create(ctx, current); err != nil {
+			return nil, err
+		}
+		history = append(history, *current)
+	case current.Revision != next-1:
+		// The spec matches an older revision, which is the case after a rollback,
+		// so that revision is promoted to the newest instead of being duplicated.
+		current.Revision = next
+		if err := r.Client.Update(ctx, current); err != nil {
+			return nil, err
+		}
+	}
+
+	// Pruning re-orders the history, so the current revision is copied
+	// out before the slice it points into is sorted.
+	recorded := current.DeepCopy()
+
+	for _, rev := range acmerevisions.Prunable(history, *cr.RevisionHistoryLimit()) {
+		if err := r.Client.Delete(ctx, &rev); err != nil && !errors.IsNotFound(err) {
+			return nil, err
+		}
+	}
+
+	return recorded, nil
+}
+
+// rollback replaces the spec of the CR with the spec recorded in the requested revision, and clears
+// the rollback request.  The spec update is what triggers the reconciliation of the restored state.
+func (r *ApplicationReconciler) rollback(ctx context.Context, cr *acmeiov1beta1.Application) error {
+	history, err := r.history(ctx, cr)
+	if err != nil {
+		return err
+	}
+
+	requested := cr.Spec.RollbackTo.Revision
+	target := acmerevisions.Find(history, requested)
+	if target == nil {
+		// A revision that is not in the history will never be found on a retry,
+		// so the request is dropped instead of being retried forever.
+		return r.dropRollback(ctx, cr, fmt.Errorf("revision %d was not found in the revision history", requested))
+	}
+
+	spec, err := acmerevisions.Spec(target)
+	if err != nil {
+		return err
+	}
+
+	// The history limit is not part of a recorded revision, and is kept as is
+	// so that a rollback never prunes more history than was asked for.  The
+	// same goes for suspension and the drift policy and ignore rules, a rollback
+	// must not resume a paused Application, or start correcting drift that is
+	// being debugged or that other actors are expected to cause.
+	spec.RevisionHistoryLimit = cr.Spec.RevisionHistoryLimit
+	spec.Suspend = cr.Spec.Suspend
+	spec.DriftPolicy = cr.Spec.DriftPolicy
+	spec.DriftIgnore = cr.Spec.DriftIgnore
+	current := cr.DeepCopy()
+	cr.Spec = *spec
+	if err := r.Client.Update(ctx, cr); err != nil {
+		// A restored spec that is rejected, such as by an image policy that was
+		// tightened since the revision was recorded, is rejected on every retry,
+		// and the pending request would hold back every other change to the CR.
+		if !isPermanent(err) && !errors.IsForbidden(err) {
+			return err
+		}
+		return r.dropRollback(ctx, current, fmt.Errorf("revision %d was rejected: %w", target.Revision, err))
+	}
+	r.Recorder.Eventf(cr, corev1.EventTypeNormal, "RolledBack", "rolled back the spec to revision %d", target.Revision)
+
+	return nil
+}
+
+// dropRollback clears the rollback request of the CR, which keeps its current spec, and returns the reason the
+// rollback was dropped as a permanent error so that it is reported on the CR.
+func (r *ApplicationReconciler) dropRollback(ctx context.Context, cr *acmeiov1beta1.Application, reason error) error {
+	cr.Spec.RollbackTo = nil
+	if err := r.Client.Update(ctx, cr); err != nil {
+		return err
+	}
+
+	return permanent(reason)
+}
@@ -0,0 +1,204 @@
+/*
+Copyright 2023 Nathan Brophy.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	acmeiov1beta1 "github.com/nathanbrophy/portfolio-demo/k8s/api/v1beta1"
+)
+
+// setImage changes the image of the CR on the cluster and reconciles the change
+func setImage(t *testing.T, cluster *fakeCluster, cr *acmeiov1beta1.Application, image string) {
+	t.Helper()
+
+	cluster.update(t, cr, func(found *acmeiov1beta1.Application) { found.Spec.Application.Image = &image })
+	if _, err := cluster.reconcile(t, cr); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+}
+
+// revisions lists the revision numbers recorded for the CR, from the oldest to the newest
+func revisions(t *testing.T, cluster *fakeCluster, cr *acmeiov1beta1.Application) []int64 {
+	t.Helper()
+
+	history, err := cluster.reconciler.history(context.Background(), cluster.application(t, cr))
+	if err != nil {
+		t.Fatalf("history() error = %v", err)
+	}
+	numbers := []int64{}
+	for _, rev := range history {
+		numbers = append(numbers, rev.Revision)
+	}
+
+	return numbers
+}
+
+func TestReconcile_RecordRevision(t *testing.T) {
+	cr := reconcilingCR("record-revision")
+	cr.Spec.RevisionHistoryLimit = func(x int32) *int32 { return &x }(1)
+	cluster := newFakeCluster(t, cr)
+	if _, err := cluster.reconcile(t, cr); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	if got := revisions(t, cluster, cr); len(got) != 1 || got[0] != 1 {
+		t.Fatalf("revisions = %v, want [1]", got)
+	}
+
+	// Reconciling the same spec again records nothing new
+	if _, err := cluster.reconcile(t, cr); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	if got := revisions(t, cluster, cr); len(got) != 1 {
+		t.Fatalf("revisions = %v, want the unchanged spec recorded once", got)
+	}
+
+	// Every changed spec is a new revision, and the history beyond the current
+	// revision is pruned down to the limit
+	setImage(t, cluster, cr, "example.com/test-image:v2.0")
+	setImage(t, cluster, cr, "example.com/test-image:v3.0")
+	if got := revisions(t, cluster, cr); len(got) != 2 || got[0] != 2 || got[1] != 3 {
+		t.Errorf("revisions = %v, want [2 3]", got)
+	}
+	found := cluster.application(t, cr)
+	if found.Status.Revision != 3 || found.Status.CurrentRevision == "" {
+		t.Errorf("status revision = %q (%d), want revision 3", found.Status.CurrentRevision, found.Status.Revision)
+	}
+}
+
+func TestReconcile_Rollback(t *testing.T) {
+	cr := reconcilingCR("rollback")
+	cluster := newFakeCluster(t, cr)
+	if _, err := cluster.reconcile(t, cr); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	setImage(t, cluster, cr, "example.com/test-image:v2.0")
+	cluster.events()
+
+	cluster.update(t, cr, func(found *acmeiov1beta1.Application) {
+		found.Spec.RollbackTo = &acmeiov1beta1.ApplicationRollback{Revision: 1}
+	})
+	if _, err := cluster.reconcile(t, cr); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	found := cluster.application(t, cr)
+	if found.Spec.RollbackTo != nil {
+		t.Errorf("spec.rollbackTo = %v, want the request cleared", found.Spec.RollbackTo)
+	}
+	if got := found.Image(); got != "example.com/test-image:v1.0" {
+		t.Errorf("image = %q, want the image of revision 1", got)
+	}
+	if events := cluster.events(); len(events) != 1 || !strings.Contains(events[0], "RolledBack") {
+		t.Errorf("events = %v, want a RolledBack event", events)
+	}
+
+	// The restored spec promotes its revision to the newest instead of being
+	// recorded again
+	if _, err := cluster.reconcile(t, cr); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	if got := revisions(t, cluster, cr); len(got) != 2 || got[0] != 2 || got[1] != 3 {
+		t.Errorf("revisions = %v, want [2 3]", got)
+	}
+	deployment := &appsv1.Deployment{}
+	if err := cluster.reconciler.Client.Get(context.Background(), client.ObjectKey{Namespace: cr.GetNamespace(), Name: acmeiov1beta1.NAME}, deployment); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got := deployment.Spec.Template.Spec.Containers[0].Image; got != "example.com/test-image:v1.0" {
+		t.Errorf("Deployment image = %q, want the rolled back image", got)
+	}
+}
+
+func TestReconcile_RollbackDropped(t *testing.T) {
+	tests := []struct {
+		name     string
+		revision int64
+		reject   bool
+	}{
+		{
+			name:     "revision not in the history",
+			revision: 7,
+		},
+		{
+			name:     "restored spec rejected",
+			revision: 1,
+			reject:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cr := reconcilingCR("rollback-dropped")
+			cluster := newFakeCluster(t, cr)
+			if _, err := cluster.reconcile(t, cr); err != nil {
+				t.Fatalf("Reconcile() error = %v", err)
+			}
+			setImage(t, cluster, cr, "example.com/test-image:v2.0")
+
+			if tt.reject {
+				// The restored image is rejected, as an admission webhook enforcing
+				// a policy tightened since revision 1 was recorded would
+				cluster.reconciler.Client = interceptor.NewClient(cluster.reconciler.Client.(client.WithWatch), interceptor.Funcs{
+					Update: func(ctx context.Context, clnt client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
+						if app, ok := obj.(*acmeiov1beta1.Application); ok && app.Image() == "example.com/test-image:v1.0" {
+							return apierrors.NewInvalid(
+								acmeiov1beta1.GroupVersion.WithKind("Application").GroupKind(),
+								app.GetName(),
+								field.ErrorList{field.Forbidden(field.NewPath("spec", "application", "image"), "tag is denied")},
+							)
+						}
+						return clnt.Update(ctx, obj, opts...)
+					},
+				})
+			}
+
+			cluster.update(t, cr, func(found *acmeiov1beta1.Application) {
+				found.Spec.RollbackTo = &acmeiov1beta1.ApplicationRollback{Revision: tt.revision}
+			})
+			if _, err := cluster.reconcile(t, cr); !errors.Is(err, reconcile.TerminalError(nil)) {
+				t.Fatalf("Reconcile() error = %v, want a terminal error", err)
+			}
+			found := cluster.application(t, cr)
+			if found.Spec.RollbackTo != nil {
+				t.Errorf("spec.rollbackTo = %v, want the request dropped", found.Spec.RollbackTo)
+			}
+			if got := found.Image(); got != "example.com/test-image:v2.0" {
+				t.Errorf("image = %q, want the spec left as it was", got)
+			}
+			if !strings.Contains(found.Status.Reason, "revision") {
+				t.Errorf("status.reason = %q, want the dropped rollback reported", found.Status.Reason)
+			}
+
+			// With the request dropped the CR is reconciled as usual again
+			if _, err := cluster.reconcile(t, cr); err != nil {
+				t.Fatalf("Reconcile() error = %v", err)
+			}
+			if got := revisions(t, cluster, cr); len(got) != 2 {
+				t.Errorf("revisions = %v, want the history left as it was", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2023 Nathan Brophy.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	acmeiov1beta1 "github.com/nathanbrophy/portfolio-demo/k8s/api/v1beta1"
+	acmegdrift "github.com/nathanbrophy/portfolio-demo/k8s/driftDetection"
+)
+
+// detectDrift loads the cluster state of every generated manifest, without changing it, and describes each
+// object that is missing from the cluster, is not up to date with a change to the CR, or has drifted from the
+// state defined by the CR, along with the records of the drift that was found
+func (r *ApplicationReconciler) detectDrift(ctx context.Context, cr *acmeiov1beta1.Application, toReconcile []ReconcileWrapper) ([]string, []acmeiov1beta1.ApplicationDriftRecord, error) {
+	drifted := []string{}
+	records := []acmeiov1beta1.ApplicationDriftRecord{}
+
+	namespace := cr.GetNamespace()
+	for _, reconcilers := range toReconcile {
+		reconcilers.Manifest.SetNamespace(namespace)
+		kind := gvk(reconcilers.Manifest).Kind
+
+		found := reconcilers.ObjectLoader
+		found.SetNamespace(namespace)
+		found.SetName(reconcilers.Manifest.GetName())
+		if err := r.getChild(ctx, found); err != nil {
+			if errors.IsNotFound(err) {
+				drifted = append(drifted, fmt.Sprintf("%s %s is missing", kind, found.GetName()))
+				continue
+			}
+			return nil, nil, err
+		}
+
+		// As when reconciling, objects the CR does not control are not its to
+		// report on, and drift is not looked for under the Ignore policy.
+		if !mayManage(cr, found) {
+			continue
+		}
+		if !metav1.IsControlledBy(found, cr) {
+			drifted = append(drifted, fmt.Sprintf("%s %s is not adopted by the Application yet", kind, found.GetName()))
+			continue
+		}
+		detect := reconcilers.Driftor
+		if cr.DriftPolicyFor(kind) == acmeiov1beta1.DriftPolicyIgnore {
+			detect = acmegdrift.Changes
+		}
+
+		report, err := detect(reconcilers.Manifest, found)
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to detect drift on %s %s: %w", kind, found.GetName(), err)
+		}
+		if report.Changed {
+			// A change to the CR that is not applied yet is reported, but is not drift
+			drifted = append(drifted, fmt.Sprintf("%s %s is not up to date with the Application", kind, found.GetName()))
+			continue
+		}
+		if report.Drifted() {
+			observeDrift(namespace, cr.GetName(), kind)
+			drifted = append(drifted, fmt.Sprintf("%s %s has drifted (%s)", kind, found.GetName(), report.String()))
+			records = append(records, driftRecord(kind, found.GetName(), report, time.Now()))
+		}
+	}
+
+	return drifted, records, nil
+}
+
+// suspendedCondition builds the Suspended condition for the CR, reporting the drift found while the
+// reconciliation is suspended
+func suspendedCondition(cr *acmeiov1beta1.Application, drifted []string) metav1.Condition {
+	condition := metav1.Condition{
+		Type:               acmeiov1beta1.ConditionSuspended,
+		Status:             metav1.ConditionFalse,
+		Reason:             "Reconciling",
+		Message:            "the cluster state is being reconciled",
+		ObservedGeneration: cr.GetGeneration(),
+	}
+	if !cr.Suspend() {
+		return condition
+	}
+
+	condition.Status = metav1.ConditionTrue
+	condition.Reason = "Suspended"
+	condition.Message = "reconciliation is suspended, no drift was detected"
+	if len(drifted) > 0 {
+		condition.Reason = "SuspendedWithDrift"
+		condition.Message = "reconciliation is suspended, drift is not corrected: " + strings.Join(drifted, ", ")
+	}
+
+	return condition
+}
@@ -0,0 +1,192 @@
+/*
+Copyright 2023 Nathan Brophy.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	acmeiov1beta1 "github.com/nathanbrophy/portfolio-demo/k8s/api/v1beta1"
+)
+
+func TestReconcile_Suspend(t *testing.T) {
+	suspend := func(suspended bool) func(*acmeiov1beta1.Application) {
+		return func(found *acmeiov1beta1.Application) { found.Spec.Suspend = &suspended }
+	}
+
+	cr := reconcilingCR("suspend")
+	suspend(true)(cr)
+	cluster := newFakeCluster(t, cr)
+	key := client.ObjectKey{Namespace: cr.GetNamespace(), Name: acmeiov1beta1.NAME}
+
+	// A suspended Application creates nothing, and reports the missing objects
+	if _, err := cluster.reconcile(t, cr); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	if len(cluster.applies) > 0 {
+		t.Errorf("applied %v, want nothing applied while suspended", cluster.applies)
+	}
+	if err := cluster.reconciler.Client.Get(context.Background(), key, &appsv1.Deployment{}); !errors.IsNotFound(err) {
+		t.Errorf("Get() error = %v, want the Deployment left uncreated", err)
+	}
+	wantSuspended(t, cluster.application(t, cr), "SuspendedWithDrift", "is missing")
+
+	cluster.update(t, cr, suspend(false))
+	if _, err := cluster.reconcile(t, cr); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	wantSuspended(t, cluster.application(t, cr), "Reconciling", "")
+	cluster.update(t, cr, suspend(true))
+	if _, err := cluster.reconcile(t, cr); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	wantSuspended(t, cluster.application(t, cr), "Suspended", "no drift")
+
+	// A hand edit is left in place, and reported as drift
+	deployment := &appsv1.Deployment{}
+	if err := cluster.reconciler.Client.Get(context.Background(), key, deployment); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	deployment.Spec.Template.Spec.Containers[0].Image = "example.com/test-image:hotfix"
+	if err := cluster.reconciler.Client.Update(context.Background(), deployment); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	cluster.applies = map[string]*client.PatchOptions{}
+	if _, err := cluster.reconcile(t, cr); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	if len(cluster.applies) > 0 {
+		t.Errorf("applied %v, want nothing applied while suspended", cluster.applies)
+	}
+	found := cluster.application(t, cr)
+	wantSuspended(t, found, "SuspendedWithDrift", "Deployment "+acmeiov1beta1.NAME+" has drifted")
+	if len(found.Status.RecentDrift) == 0 {
+		t.Errorf("status.recentDrift is empty, want the drift recorded")
+	}
+
+	// A change to the Application is not applied either, and is reported as
+	// not up to date instead of as drift
+	cluster.update(t, cr, func(found *acmeiov1beta1.Application) {
+		found.Spec.Application.Replicas = func(x int32) *int32 { return &x }(5)
+	})
+	if _, err := cluster.reconcile(t, cr); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	if len(cluster.applies) > 0 {
+		t.Errorf("applied %v, want nothing applied while suspended", cluster.applies)
+	}
+	if err := cluster.reconciler.Client.Get(context.Background(), key, deployment); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if deployment.Spec.Replicas != nil && *deployment.Spec.Replicas == 5 {
+		t.Errorf("Deployment replicas = 5, want the change held back while suspended")
+	}
+	wantSuspended(t, cluster.application(t, cr), "SuspendedWithDrift", "Deployment "+acmeiov1beta1.NAME+" is not up to date")
+}
+
+// wantSuspended checks the reason of the Suspended condition of the CR, and that its message holds the given text
+func wantSuspended(t *testing.T, cr *acmeiov1beta1.Application, reason, message string) {
+	t.Helper()
+
+	condition := meta.FindStatusCondition(cr.Status.Conditions, acmeiov1beta1.ConditionSuspended)
+	if condition == nil {
+		t.Fatalf("status.conditions has no %s condition", acmeiov1beta1.ConditionSuspended)
+	}
+	if condition.Reason != reason || !strings.Contains(condition.Message, message) {
+		t.Errorf("%s condition = %s: %q, want %s: %q", acmeiov1beta1.ConditionSuspended, condition.Reason, condition.Message, reason, message)
+	}
+}
+
+func TestReconcile_SuspendedDriftFilters(t *testing.T) {
+	tests := []struct {
+		name         string
+		policy       *acmeiov1beta1.ApplicationDriftPolicy
+		uncontrolled bool
+		wantReason   string
+		wantMessage  string
+		notMessage   string
+	}{
+		{
+			name:        "Correct policy",
+			wantReason:  "SuspendedWithDrift",
+			wantMessage: "Deployment " + acmeiov1beta1.NAME + " has drifted",
+		},
+		{
+			name: "Ignore policy",
+			policy: &acmeiov1beta1.ApplicationDriftPolicy{
+				Kinds: []acmeiov1beta1.ApplicationKindDriftPolicy{{Kind: "Deployment", Policy: acmeiov1beta1.DriftPolicyIgnore}},
+			},
+			wantReason: "Suspended",
+			notMessage: "Deployment",
+		},
+		{
+			name:         "object the Application does not control",
+			uncontrolled: true,
+			wantReason:   "Suspended",
+			notMessage:   "Deployment",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cr := reconcilingCR("suspended-drift")
+			cr.Spec.DriftPolicy = tt.policy
+			cluster := newFakeCluster(t, cr)
+			if _, err := cluster.reconcile(t, cr); err != nil {
+				t.Fatalf("Reconcile() error = %v", err)
+			}
+			cluster.update(t, cr, func(found *acmeiov1beta1.Application) {
+				suspended := true
+				found.Spec.Suspend = &suspended
+			})
+
+			// The Deployment is edited by hand, and is taken over by something
+			// else for the object the Application does not control
+			key := client.ObjectKey{Namespace: cr.GetNamespace(), Name: acmeiov1beta1.NAME}
+			deployment := &appsv1.Deployment{}
+			if err := cluster.reconciler.Client.Get(context.Background(), key, deployment); err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			deployment.Spec.Template.Spec.Containers[0].Image = "example.com/test-image:hotfix"
+			if tt.uncontrolled {
+				deployment.SetOwnerReferences(nil)
+			}
+			if err := cluster.reconciler.Client.Update(context.Background(), deployment); err != nil {
+				t.Fatalf("Update() error = %v", err)
+			}
+
+			if _, err := cluster.reconcile(t, cr); err != nil {
+				t.Fatalf("Reconcile() error = %v", err)
+			}
+			found := cluster.application(t, cr)
+			wantSuspended(t, found, tt.wantReason, tt.wantMessage)
+			condition := meta.FindStatusCondition(found.Status.Conditions, acmeiov1beta1.ConditionSuspended)
+			if tt.notMessage != "" && strings.Contains(condition.Message, tt.notMessage) {
+				t.Errorf("%s condition message = %q, want nothing reported for the %s", acmeiov1beta1.ConditionSuspended, condition.Message, tt.notMessage)
+			}
+			wantDrift := tt.wantReason == "SuspendedWithDrift"
+			if got := len(found.Status.RecentDrift) > 0; got != wantDrift {
+				t.Errorf("status.recentDrift = %v, want drift recorded %v", found.Status.RecentDrift, wantDrift)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+/*
+Copyright 2023 Nathan Brophy.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// tracer creates the spans of every reconciliation, through the global tracer provider, which does not record any
+// span unless tracing was set up by the manager
+var tracer = otel.Tracer("github.com/nathanbrophy/portfolio-demo/k8s/controllers")
+
+// applicationAttributes identifies the CR a span belongs to
+func applicationAttributes(key types.NamespacedName) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("acme.application.namespace", key.Namespace),
+		attribute.String("acme.application.name", key.Name),
+	}
+}
+
+// objectAttributes identifies the downstream object a span belongs to
+func objectAttributes(objGVK schema.GroupVersionKind, name string) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("k8s.object.group", objGVK.Group),
+		attribute.String("k8s.object.version", objGVK.Version),
+		attribute.String("k8s.object.kind", objGVK.Kind),
+		attribute.String("k8s.object.name", name),
+	}
+}
+
+// endSpan records the error, if any, as the outcome of the span and ends it
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -1,6 +1,7 @@
 package driftdetection
 
 import (
+	"fmt"
 	"reflect"
 
 	appsv1 "k8s.io/api/apps/v1"
@@ -15,45 +16,84 @@ import (
 //	in: The object we have reconciled from the desired cluster state
 //	out: The object that already exists on the cluster we must reconcile againse
 //
-// and perform a deep comparision on thetwo objects to determine if any such drift exists
-type DriftDetectionFunc func(in, out client.Object) bool
+// and perform a deep comparision on thetwo objects to report every field where such drift exists.  An error is
+// returned, instead of a report, when the two objects cannot be compared, such as when either is nil or of the wrong kind.
+type DriftDetectionFunc func(in, out client.Object) (Report, error)
 
 // Deployment implements DriftDetectionFunc for the deployment resource
-func Deployment(in, out client.Object) bool {
-	lhs := in.(*appsv1.Deployment)
-	rhs := out.(*appsv1.Deployment)
+func Deployment(in, out client.Object) (Report, error) {
+	lhs, rhs, err := cast[*appsv1.Deployment](in, out)
+	if err != nil {
+		return Report{}, err
+	}
 
-	drift := *lhs.Spec.Replicas != *rhs.Spec.Replicas
-	drift = drift || !reflect.DeepEqual(lhs.Spec.Selector, rhs.Spec.Selector)
-	drift = drift || !reflect.DeepEqual(lhs.Spec.Template.Labels, rhs.Spec.Template.Labels)
-	drift = drift || !reflect.DeepEqual(lhs.Spec.Template.Spec.Containers, rhs.Spec.Template.Spec.Containers)
+	report := Report{}
+	report.compare("/spec/replicas", lhs.Spec.Replicas, rhs.Spec.Replicas)
+	report.compare("/spec/selector", lhs.Spec.Selector, rhs.Spec.Selector)
+	report.compare("/spec/template/metadata/labels", lhs.Spec.Template.Labels, rhs.Spec.Template.Labels)
+	report.compare("/spec/template/spec/containers", lhs.Spec.Template.Spec.Containers, rhs.Spec.Template.Spec.Containers)
 
-	return drift
+	return report, nil
 }
 
 // Service implements DriftDetectionFunc for the Service resource
-func Service(in, out client.Object) bool {
-	lhs := in.(*corev1.Service)
-	rhs := out.(*corev1.Service)
+func Service(in, out client.Object) (Report, error) {
+	lhs, rhs, err := cast[*corev1.Service](in, out)
+	if err != nil {
+		return Report{}, err
+	}
 
-	drift := !reflect.DeepEqual(lhs.Spec.Selector, rhs.Spec.Selector)
-	drift = drift || !reflect.DeepEqual(lhs.Spec.Ports, rhs.Spec.Ports)
+	report := Report{}
+	report.compare("/spec/selector", lhs.Spec.Selector, rhs.Spec.Selector)
+	report.compare("/spec/ports", lhs.Spec.Ports, rhs.Spec.Ports)
 
-	return drift
+	return report, nil
 }
 
 // ServiceAccount implements DriftDetectionFunc for the ServiceAccount resource
-func ServiceAccount(in, out client.Object) bool {
-	lhs := in.(*corev1.ServiceAccount)
-	rhs := out.(*corev1.ServiceAccount)
+func ServiceAccount(in, out client.Object) (Report, error) {
+	lhs, rhs, err := cast[*corev1.ServiceAccount](in, out)
+	if err != nil {
+		return Report{}, err
+	}
 
-	return !reflect.DeepEqual(lhs.ImagePullSecrets, rhs.ImagePullSecrets)
+	report := Report{}
+	report.compare("/imagePullSecrets", lhs.ImagePullSecrets, rhs.ImagePullSecrets)
+
+	return report, nil
 }
 
 // Ingress implements DriftDetectionFunc for the ServiceAccount resource
-func Ingress(in, out client.Object) bool {
-	lhs := in.(*networkingv1.Ingress)
-	rhs := out.(*networkingv1.Ingress)
+func Ingress(in, out client.Object) (Report, error) {
+	lhs, rhs, err := cast[*networkingv1.Ingress](in, out)
+	if err != nil {
+		return Report{}, err
+	}
+
+	report := Report{}
+	report.compare("/spec/rules", lhs.Spec.Rules, rhs.Spec.Rules)
+
+	return report, nil
+}
+
+// cast asserts that both objects are non nil objects of the kind a detection function compares
+func cast[T client.Object](in, out client.Object) (T, T, error) {
+	lhs, lok := in.(T)
+	rhs, rok := out.(T)
+	if !lok || !rok || isNil(lhs) || isNil(rhs) {
+		var zero T
+		return zero, zero, fmt.Errorf("cannot compare a %T to a %T, expected two %T objects", in, out, zero)
+	}
+
+	return lhs, rhs, nil
+}
+
+// isNil reports if an object is nil, including a nil pointer wrapped in a non nil interface
+func isNil(obj client.Object) bool {
+	if obj == nil {
+		return true
+	}
+	value := reflect.ValueOf(obj)
 
-	return !reflect.DeepEqual(lhs.Spec.Rules, rhs.Spec.Rules)
+	return value.Kind() == reflect.Pointer && value.IsNil()
 }
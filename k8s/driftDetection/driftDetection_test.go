@@ -107,7 +107,11 @@ func TestDeployment(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := Deployment(tt.args.in, tt.args.out); got != tt.want {
+			report, err := Deployment(tt.args.in, tt.args.out)
+			if err != nil {
+				t.Fatalf("Deployment() error = %v", err)
+			}
+			if got := report.Drifted(); got != tt.want {
 				t.Errorf("Deployment() = %v, want %v", got, tt.want)
 			}
 		})
@@ -169,7 +173,11 @@ func TestService(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := Service(tt.args.in, tt.args.out); got != tt.want {
+			report, err := Service(tt.args.in, tt.args.out)
+			if err != nil {
+				t.Fatalf("Service() error = %v", err)
+			}
+			if got := report.Drifted(); got != tt.want {
 				t.Errorf("Service() = %v, want %v", got, tt.want)
 			}
 		})
@@ -224,7 +232,11 @@ func TestServiceAccount(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := ServiceAccount(tt.args.in, tt.args.out); got != tt.want {
+			report, err := ServiceAccount(tt.args.in, tt.args.out)
+			if err != nil {
+				t.Fatalf("ServiceAccount() error = %v", err)
+			}
+			if got := report.Drifted(); got != tt.want {
 				t.Errorf("ServiceAccount() = %v, want %v", got, tt.want)
 			}
 		})
@@ -304,9 +316,68 @@ func TestIngress(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := Ingress(tt.args.in, tt.args.out); got != tt.want {
+			report, err := Ingress(tt.args.in, tt.args.out)
+			if err != nil {
+				t.Fatalf("Ingress() error = %v", err)
+			}
+			if got := report.Drifted(); got != tt.want {
 				t.Errorf("Ingress() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
+
+func TestDriftDetectionFunc_Uncomparable(t *testing.T) {
+	var nilDeployment *appsv1.Deployment
+
+	detectors := map[string]DriftDetectionFunc{
+		"Deployment":     Deployment,
+		"Service":        Service,
+		"ServiceAccount": ServiceAccount,
+		"Ingress":        Ingress,
+		"Generic":        Generic,
+	}
+	tests := []struct {
+		name string
+		in   client.Object
+		out  client.Object
+	}{
+		{
+			name: "nil object",
+			in:   &appsv1.Deployment{},
+			out:  nil,
+		},
+		{
+			name: "nil pointer",
+			in:   nilDeployment,
+			out:  &appsv1.Deployment{},
+		},
+		{
+			name: "mismatched kinds",
+			in:   &corev1.Service{},
+			out:  &networkingv1.Ingress{},
+		},
+	}
+	for name, detect := range detectors {
+		for _, tt := range tests {
+			t.Run(name+" "+tt.name, func(t *testing.T) {
+				if _, err := detect(tt.in, tt.out); err == nil {
+					t.Errorf("%s() error = nil, want an error", name)
+				}
+			})
+		}
+	}
+}
+
+func TestDeployment_NilFields(t *testing.T) {
+	in := &appsv1.Deployment{Spec: appsv1.DeploymentSpec{Replicas: func(x int32) *int32 { return &x }(1)}}
+	out := &appsv1.Deployment{}
+
+	report, err := Deployment(in, out)
+	if err != nil {
+		t.Fatalf("Deployment() error = %v", err)
+	}
+	if !report.Drifted() {
+		t.Errorf("Deployment() = %v, want drift on unset replicas", report.Drifted())
+	}
+}
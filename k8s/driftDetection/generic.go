@@ -0,0 +1,300 @@
+package driftdetection
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"reflect"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// HashAnnotation is set on every applied object to the hash of the manifest it was generated as
+const HashAnnotation string = "acme.io/spec-hash"
+
+// Generic implements DriftDetectionFunc for any kind of object.  The object has drifted when any field set in the
+// manifest has a different value on the cluster, unless it was applied from a different manifest than the one generated
+// now, going by its hash annotation, which is reported as a change instead.  Fields the manifest leaves out, such as
+// the ones defaulted by the API server, are not compared.
+func Generic(in, out client.Object) (Report, error) {
+	if isNil(in) || isNil(out) {
+		return Report{}, fmt.Errorf("cannot compare a %T to a %T, expected two objects", in, out)
+	}
+	if reflect.TypeOf(in) != reflect.TypeOf(out) {
+		return Report{}, fmt.Errorf("cannot compare a %T to a %T, expected two objects of the same kind", in, out)
+	}
+
+	report := Report{}
+
+	want, err := normalize(in)
+	if err != nil {
+		return Report{}, err
+	}
+	live, err := normalize(out)
+	if err != nil {
+		return Report{}, err
+	}
+
+	hash, err := hashOf(want)
+	if err != nil {
+		return Report{}, err
+	}
+	report.Changed = out.GetAnnotations()[HashAnnotation] != hash
+
+	diff(&report, "", want, live)
+
+	return report, nil
+}
+
+// Hash computes the hash of a generated manifest, over the fields that Generic compares
+func Hash(obj client.Object) (string, error) {
+	if isNil(obj) {
+		return "", fmt.Errorf("cannot hash a nil %T", obj)
+	}
+
+	normalized, err := normalize(obj)
+	if err != nil {
+		return "", err
+	}
+
+	return hashOf(normalized)
+}
+
+// Changes implements DriftDetectionFunc for objects whose drift is ignored.  Only the hash annotation is compared, so
+// that a change to the generated manifest is reported, but no field of the object is ever reported as drift.
+func Changes(in, out client.Object) (Report, error) {
+	if isNil(out) {
+		return Report{}, fmt.Errorf("cannot compare a %T to a nil %T", in, out)
+	}
+
+	hash, err := Hash(in)
+	if err != nil {
+		return Report{}, err
+	}
+
+	return Report{Changed: out.GetAnnotations()[HashAnnotation] != hash}, nil
+}
+
+// Stamp sets the hash annotation on a generated manifest, so that Generic can tell which manifest an object was applied from
+func Stamp(obj client.Object) error {
+	hash, err := Hash(obj)
+	if err != nil {
+		return err
+	}
+
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[HashAnnotation] = hash
+	obj.SetAnnotations(annotations)
+
+	return nil
+}
+
+func hashOf(normalized map[string]interface{}) (string, error) {
+	raw, err := json.Marshal(normalized)
+	if err != nil {
+		return "", err
+	}
+
+	hasher := fnv.New32a()
+	hasher.Write(raw)
+
+	return rand.SafeEncodeString(fmt.Sprint(hasher.Sum32())), nil
+}
+
+// normalize converts an object to its unstructured form, keeping only the labels and annotations of the metadata, and
+// dropping the status along with every empty field, so that a generated manifest and a live object compare alike
+func normalize(obj client.Object) (map[string]interface{}, error) {
+	// The content of an unstructured object is the object itself, and is copied
+	// so that the object is left as it is
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj.DeepCopyObject())
+	if err != nil {
+		return nil, err
+	}
+	dropOmitted(reflect.ValueOf(obj), content)
+
+	delete(content, "apiVersion")
+	delete(content, "kind")
+	delete(content, "status")
+
+	metadata := map[string]interface{}{}
+	if found, ok := content["metadata"].(map[string]interface{}); ok {
+		metadata["labels"] = found["labels"]
+		if annotations, ok := found["annotations"].(map[string]interface{}); ok {
+			kept := map[string]interface{}{}
+			for key, value := range annotations {
+				if key != HashAnnotation {
+					kept[key] = value
+				}
+			}
+			metadata["annotations"] = kept
+		}
+	}
+	content["metadata"] = metadata
+
+	pruned, _ := prune(content).(map[string]interface{})
+	if pruned == nil {
+		pruned = map[string]interface{}{}
+	}
+
+	return pruned, nil
+}
+
+// dropOmitted removes the fields of a typed object that are tagged omitempty and left at their zero value from its
+// unstructured form.  The unstructured converter keeps zero structs with custom encodings, such as an unset
+// IntOrString target port, which would otherwise be compared as 0 against the value defaulted by the API server.
+func dropOmitted(value reflect.Value, content interface{}) {
+	for value.Kind() == reflect.Pointer || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return
+		}
+		value = value.Elem()
+	}
+
+	switch value.Kind() {
+	case reflect.Struct:
+		fields, ok := content.(map[string]interface{})
+		if !ok {
+			return
+		}
+		for i := 0; i < value.NumField(); i++ {
+			field := value.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if field.Anonymous && name == "" {
+				dropOmitted(value.Field(i), fields)
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			if strings.Contains(options, "omitempty") && value.Field(i).IsZero() {
+				delete(fields, name)
+				continue
+			}
+			dropOmitted(value.Field(i), fields[name])
+		}
+	case reflect.Slice, reflect.Array:
+		items, ok := content.([]interface{})
+		if !ok || len(items) != value.Len() {
+			return
+		}
+		for i := range items {
+			dropOmitted(value.Index(i), items[i])
+		}
+	}
+}
+
+// prune drops the nil values, and the maps and lists left empty, from an unstructured value
+func prune(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		out := map[string]interface{}{}
+		for key, field := range typed {
+			if field = prune(field); field != nil {
+				out[key] = field
+			}
+		}
+		if len(out) == 0 {
+			return nil
+		}
+		return out
+	case []interface{}:
+		out := []interface{}{}
+		for _, item := range typed {
+			if item = prune(item); item != nil {
+				out = append(out, item)
+			}
+		}
+		if len(out) == 0 {
+			return nil
+		}
+		return out
+	default:
+		return value
+	}
+}
+
+// diff reports every field set in the wanted value that has another value in the live value.  List items that all
+// carry a name are matched by name, the way the API server merges them, so items added to the list by other actors,
+// such as injected sidecar containers, are not counted as drift.
+func diff(report *Report, path string, want, live interface{}) {
+	switch typed := want.(type) {
+	case map[string]interface{}:
+		found, ok := live.(map[string]interface{})
+		if !ok {
+			report.add(path, want, live)
+			return
+		}
+		keys := make([]string, 0, len(typed))
+		for key := range typed {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			diff(report, path+"/"+escape(key), typed[key], found[key])
+		}
+	case []interface{}:
+		found, ok := live.([]interface{})
+		if !ok {
+			report.add(path, want, live)
+			return
+		}
+		if names, ok := itemNames(typed); ok {
+			foundNames, _ := itemNames(found)
+			foundIndex := map[string]int{}
+			for i, name := range foundNames {
+				foundIndex[name] = i
+			}
+			for i, item := range typed {
+				index, ok := foundIndex[names[i]]
+				if !ok {
+					report.add(fmt.Sprintf("%s/%d", path, i), item, nil)
+					continue
+				}
+				diff(report, fmt.Sprintf("%s/%d", path, index), item, found[index])
+			}
+			return
+		}
+		if len(typed) != len(found) {
+			report.add(path, want, live)
+			return
+		}
+		for i, item := range typed {
+			diff(report, fmt.Sprintf("%s/%d", path, i), item, found[i])
+		}
+	default:
+		if !reflect.DeepEqual(want, live) {
+			report.add(path, want, live)
+		}
+	}
+}
+
+// itemNames lists the name of every item in a list, for as long as the items carry a name, and reports if every item does
+func itemNames(items []interface{}) ([]string, bool) {
+	names := []string{}
+	for _, item := range items {
+		fields, ok := item.(map[string]interface{})
+		if !ok {
+			return names, false
+		}
+		name, ok := fields["name"].(string)
+		if !ok {
+			return names, false
+		}
+		names = append(names, name)
+	}
+
+	return names, true
+}
@@ -0,0 +1,317 @@
+package driftdetection
+
+import (
+	"reflect"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	acmeioutils "github.com/nathanbrophy/portfolio-demo/k8s/utils"
+)
+
+func genericDeployment() *appsv1.Deployment {
+	labels := map[string]string{"app": "acme-application"}
+
+	return &appsv1.Deployment{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Deployment",
+			APIVersion: "apps/v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "acme-application",
+			Labels: labels,
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: acmeioutils.Int32PointerGenerator(2),
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:  "acme-application",
+							Image: "quay.io/acme/app:v1.0.0",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func genericService() *corev1.Service {
+	return &corev1.Service{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Service",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "acme-application",
+		},
+		Spec: corev1.ServiceSpec{
+			Selector: map[string]string{"app": "acme-application"},
+			Ports: []corev1.ServicePort{
+				{
+					Port: 8081,
+				},
+			},
+		},
+	}
+}
+
+// applied returns the object as it would be read back from the cluster after it was stamped and applied
+func applied(obj client.Object, mutate func(client.Object)) client.Object {
+	live := obj.DeepCopyObject().(client.Object)
+	if err := Stamp(live); err != nil {
+		panic(err)
+	}
+	// Typed objects read from the cache carry no type meta
+	live.GetObjectKind().SetGroupVersionKind(schema.GroupVersionKind{})
+	live.SetResourceVersion("42")
+	if mutate != nil {
+		mutate(live)
+	}
+
+	return live
+}
+
+func TestGeneric(t *testing.T) {
+	tests := []struct {
+		name        string
+		in          client.Object
+		out         client.Object
+		want        bool
+		wantChanged bool
+	}{
+		{
+			name: "applied as generated",
+			in:   genericDeployment(),
+			out:  applied(genericDeployment(), nil),
+			want: false,
+		},
+		{
+			name: "server defaulted fields",
+			in:   genericService(),
+			out: applied(genericService(), func(obj client.Object) {
+				svc := obj.(*corev1.Service)
+				svc.Spec.Type = corev1.ServiceTypeClusterIP
+				svc.Spec.ClusterIP = "10.0.0.12"
+				svc.Spec.Ports[0].Protocol = corev1.ProtocolTCP
+				svc.Spec.Ports[0].TargetPort = intstr.FromInt(8081)
+			}),
+			want: false,
+		},
+		{
+			name: "fields set by other actors",
+			in:   genericDeployment(),
+			out: applied(genericDeployment(), func(obj client.Object) {
+				deployment := obj.(*appsv1.Deployment)
+				deployment.Annotations["deployment.kubernetes.io/revision"] = "3"
+				deployment.Spec.Template.Spec.Containers = append(deployment.Spec.Template.Spec.Containers, corev1.Container{
+					Name:  "istio-proxy",
+					Image: "docker.io/istio/proxyv2:1.18.0",
+				})
+			}),
+			want: false,
+		},
+		{
+			name: "generated field changed on the cluster",
+			in:   genericDeployment(),
+			out: applied(genericDeployment(), func(obj client.Object) {
+				obj.(*appsv1.Deployment).Spec.Template.Spec.Containers[0].Image = "quay.io/acme/app:hotfix"
+			}),
+			want: true,
+		},
+		{
+			name: "scaled up while scaled down to zero",
+			in: func() client.Object {
+				deployment := genericDeployment()
+				deployment.Spec.Replicas = acmeioutils.Int32PointerGenerator(0)
+				return deployment
+			}(),
+			out: func() client.Object {
+				deployment := genericDeployment()
+				deployment.Spec.Replicas = acmeioutils.Int32PointerGenerator(0)
+				return applied(deployment, func(obj client.Object) {
+					obj.(*appsv1.Deployment).Spec.Replicas = acmeioutils.Int32PointerGenerator(3)
+				})
+			}(),
+			want: true,
+		},
+		{
+			name: "generated label removed on the cluster",
+			in:   genericDeployment(),
+			out: applied(genericDeployment(), func(obj client.Object) {
+				obj.SetLabels(nil)
+			}),
+			want: true,
+		},
+		{
+			name: "applied from another manifest",
+			in:   genericDeployment(),
+			out: applied(genericDeployment(), func(obj client.Object) {
+				obj.GetAnnotations()[HashAnnotation] = "stale"
+			}),
+			wantChanged: true,
+		},
+		{
+			name: "manifest changed since it was applied",
+			in: func() client.Object {
+				deployment := genericDeployment()
+				deployment.Spec.Replicas = acmeioutils.Int32PointerGenerator(5)
+				return deployment
+			}(),
+			out:         applied(genericDeployment(), nil),
+			wantChanged: true,
+		},
+		{
+			name: "never stamped",
+			in:   genericService(),
+			out: applied(genericService(), func(obj client.Object) {
+				obj.SetAnnotations(nil)
+			}),
+			wantChanged: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, err := Generic(tt.in, tt.out)
+			if err != nil {
+				t.Fatalf("Generic() error = %v", err)
+			}
+			if got := report.Drifted(); got != tt.want {
+				t.Errorf("Generic() = %v, want %v", got, tt.want)
+			}
+			if report.Changed != tt.wantChanged {
+				t.Errorf("Generic() changed = %v, want %v", report.Changed, tt.wantChanged)
+			}
+		})
+	}
+}
+
+func TestHash(t *testing.T) {
+	base, err := Hash(genericDeployment())
+	if err != nil {
+		t.Fatalf("Hash() error = %v", err)
+	}
+
+	stamped := genericDeployment()
+	if err := Stamp(stamped); err != nil {
+		t.Fatalf("Stamp() error = %v", err)
+	}
+	if got, _ := Hash(stamped); got != base {
+		t.Errorf("Hash() of a stamped manifest = %v, want %v", got, base)
+	}
+
+	changed := genericDeployment()
+	changed.Spec.Replicas = acmeioutils.Int32PointerGenerator(0)
+	if got, _ := Hash(changed); got == base {
+		t.Errorf("Hash() of a changed manifest = %v, want a different hash", got)
+	}
+}
+
+func TestGeneric_Unstructured(t *testing.T) {
+	in := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata":   map[string]interface{}{"name": "settings", "namespace": "default"},
+		"data":       map[string]interface{}{"mode": "fast"},
+	}}
+	original := in.DeepCopy()
+	if err := Stamp(in); err != nil {
+		t.Fatalf("Stamp() error = %v", err)
+	}
+	if in.GetName() != original.GetName() || in.GetAPIVersion() != original.GetAPIVersion() || !reflect.DeepEqual(in.Object["data"], original.Object["data"]) {
+		t.Errorf("Stamp() = %v, want the unstructured object left as it is apart from its hash", in.Object)
+	}
+
+	out := in.DeepCopy()
+	out.Object["data"] = map[string]interface{}{"mode": "slow", "added": "by hand"}
+	report, err := Generic(in, out)
+	if err != nil {
+		t.Fatalf("Generic() error = %v", err)
+	}
+	want := []Difference{{Path: "/data/mode", Expected: `"fast"`, Actual: `"slow"`}}
+	if !reflect.DeepEqual(report.Differences, want) {
+		t.Errorf("Generic() differences = %v, want %v", report.Differences, want)
+	}
+}
+
+func TestGeneric_Report(t *testing.T) {
+	in := genericDeployment()
+	out := applied(genericDeployment(), func(obj client.Object) {
+		deployment := obj.(*appsv1.Deployment)
+		deployment.Spec.Replicas = acmeioutils.Int32PointerGenerator(5)
+		deployment.Spec.Template.Spec.Containers = append([]corev1.Container{{Name: "istio-proxy"}}, deployment.Spec.Template.Spec.Containers...)
+		deployment.Spec.Template.Spec.Containers[1].Image = "quay.io/acme/app:hotfix"
+	})
+
+	want := []Difference{
+		{Path: "/spec/replicas", Expected: "2", Actual: "5"},
+		{Path: "/spec/template/spec/containers/1/image", Expected: `"quay.io/acme/app:v1.0.0"`, Actual: `"quay.io/acme/app:hotfix"`},
+	}
+	report, err := Generic(in, out)
+	if err != nil {
+		t.Fatalf("Generic() error = %v", err)
+	}
+	if got := report.Differences; !reflect.DeepEqual(got, want) {
+		t.Errorf("Generic() differences = %v, want %v", got, want)
+	}
+}
+
+func TestChanges(t *testing.T) {
+	changed := genericDeployment()
+	changed.Spec.Replicas = acmeioutils.Int32PointerGenerator(5)
+
+	tests := []struct {
+		name    string
+		in      client.Object
+		out     client.Object
+		want    bool
+		wantErr bool
+	}{
+		{
+			name: "applied from the same manifest",
+			in:   genericDeployment(),
+			out:  applied(genericDeployment(), nil),
+			want: false,
+		},
+		{
+			name: "edited on the cluster only",
+			in:   genericDeployment(),
+			out: applied(genericDeployment(), func(obj client.Object) {
+				obj.(*appsv1.Deployment).Spec.Replicas = acmeioutils.Int32PointerGenerator(3)
+			}),
+			want: false,
+		},
+		{
+			name: "applied from another manifest",
+			in:   changed,
+			out:  applied(genericDeployment(), nil),
+			want: true,
+		},
+		{
+			name:    "nil object",
+			in:      genericDeployment(),
+			out:     nil,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, err := Changes(tt.in, tt.out)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Changes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if report.Changed != tt.want || report.Drifted() {
+				t.Errorf("Changes() = %+v, want changed %v and no drift", report, tt.want)
+			}
+		})
+	}
+}
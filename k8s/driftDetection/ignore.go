@@ -0,0 +1,119 @@
+package driftdetection
+
+import (
+	"fmt"
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	acmefieldpath "github.com/nathanbrophy/portfolio-demo/k8s/fieldpath"
+)
+
+// IgnoreRule excludes a field, along with every field below it, from drift detection.  Fields that other actors
+// legitimately set, such as injected sidecar containers or annotations added by a load balancer controller, are
+// ignored so that the controller does not keep taking them back.
+type IgnoreRule struct {
+	// Kind limits the rule to a single kind of object, the rule applies to every kind when empty
+	Kind string
+
+	// Path is the field to ignore as a list of reference tokens, a * token matches any single token
+	Path []string
+}
+
+// IgnoreRules is a list of rules that are applied together
+type IgnoreRules []IgnoreRule
+
+// ParseIgnoreRule parses a field to ignore for the given kind, given as a JSON pointer or a field path as described by
+// acmefieldpath.Parse
+func ParseIgnoreRule(kind, path string) (IgnoreRule, error) {
+	tokens, err := acmefieldpath.Parse(path)
+	if err != nil {
+		return IgnoreRule{}, fmt.Errorf("invalid drift ignore rule: %w", err)
+	}
+
+	return IgnoreRule{Kind: kind, Path: tokens}, nil
+}
+
+// ParseIgnoreRules parses a comma separated list of fields to ignore, where each field is optionally prefixed with the
+// kind it applies to, such as Deployment=spec.template.spec.containers
+func ParseIgnoreRules(in string) (IgnoreRules, error) {
+	rules := IgnoreRules{}
+	for _, entry := range strings.Split(in, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		kind := ""
+		if before, after, found := strings.Cut(entry, "="); found && isKind(before) {
+			kind, entry = before, after
+		}
+
+		rule, err := ParseIgnoreRule(kind, entry)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+// Ignoring wraps a drift detection function so that the differences in the fields ignored by the rules are left out
+// of its report.  A change to the generated manifest is still reported, as it is not drift.
+func Ignoring(detect DriftDetectionFunc, rules IgnoreRules) DriftDetectionFunc {
+	return func(in, out client.Object) (Report, error) {
+		report, err := detect(in, out)
+		if err != nil {
+			return report, err
+		}
+
+		kind := in.GetObjectKind().GroupVersionKind().Kind
+		kept := []Difference{}
+		for _, difference := range report.Differences {
+			if !rules.ignores(kind, difference.Path) {
+				kept = append(kept, difference)
+			}
+		}
+		report.Differences = nil
+		if len(kept) > 0 {
+			report.Differences = kept
+		}
+
+		return report, nil
+	}
+}
+
+// ignores reports if any rule for the kind matches the JSON pointer, or one of its parents
+func (rules IgnoreRules) ignores(kind, pointer string) bool {
+	tokens, err := acmefieldpath.ParsePointer(pointer)
+	if err != nil {
+		return false
+	}
+
+	for _, rule := range rules {
+		if rule.Kind != "" && rule.Kind != kind {
+			continue
+		}
+		if acmefieldpath.Matches(rule.Path, tokens) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isKind reports if a value is shaped like the kind of an object, which distinguishes the kind prefix of a rule from a
+// field path with an = in one of its keys
+func isKind(value string) bool {
+	if value == "" || value[0] < 'A' || value[0] > 'Z' {
+		return false
+	}
+	for _, c := range value {
+		if (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,161 @@
+package driftdetection
+
+import (
+	"reflect"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	acmeioutils "github.com/nathanbrophy/portfolio-demo/k8s/utils"
+)
+
+func TestParseIgnoreRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    IgnoreRules
+		wantErr bool
+	}{
+		{
+			name: "empty",
+			in:   "",
+			want: IgnoreRules{},
+		},
+		{
+			name: "json pointers",
+			in:   "/spec/replicas, Ingress=/metadata/annotations/alb.ingress.kubernetes.io~1scheme",
+			want: IgnoreRules{
+				{Path: []string{"spec", "replicas"}},
+				{Kind: "Ingress", Path: []string{"metadata", "annotations", "alb.ingress.kubernetes.io/scheme"}},
+			},
+		},
+		{
+			name: "field paths",
+			in:   "Deployment=spec.template.spec.containers[*].image,metadata.annotations[sidecar.istio.io/status]",
+			want: IgnoreRules{
+				{Kind: "Deployment", Path: []string{"spec", "template", "spec", "containers", "*", "image"}},
+				{Path: []string{"metadata", "annotations", "sidecar.istio.io/status"}},
+			},
+		},
+		{
+			name: "equals sign in a key",
+			in:   "metadata.labels[a=b]",
+			want: IgnoreRules{
+				{Path: []string{"metadata", "labels", "a=b"}},
+			},
+		},
+		{
+			name:    "empty field",
+			in:      "spec..replicas",
+			wantErr: true,
+		},
+		{
+			name:    "unclosed key",
+			in:      "metadata.annotations[acme.io/owner",
+			wantErr: true,
+		},
+		{
+			name:    "empty reference token",
+			in:      "/spec//replicas",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseIgnoreRules(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseIgnoreRules() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseIgnoreRules() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIgnoring(t *testing.T) {
+	sidecar := func(obj client.Object) {
+		deployment := obj.(*appsv1.Deployment)
+		deployment.Spec.Replicas = acmeioutils.Int32PointerGenerator(5)
+		deployment.Spec.Template.Spec.Containers[0].Image = "quay.io/acme/app:hotfix"
+	}
+
+	tests := []struct {
+		name        string
+		rules       string
+		out         client.Object
+		want        []string
+		wantChanged bool
+	}{
+		{
+			name:  "no rules",
+			rules: "",
+			out:   applied(genericDeployment(), sidecar),
+			want:  []string{"/spec/replicas", "/spec/template/spec/containers/0/image"},
+		},
+		{
+			name:  "parent field",
+			rules: "Deployment=spec.template.spec.containers",
+			out:   applied(genericDeployment(), sidecar),
+			want:  []string{"/spec/replicas"},
+		},
+		{
+			name:  "wildcard",
+			rules: "/spec/template/spec/containers/*/image,/spec/replicas",
+			out:   applied(genericDeployment(), sidecar),
+			want:  nil,
+		},
+		{
+			name:  "rule for another kind",
+			rules: "Service=/spec/replicas",
+			out:   applied(genericDeployment(), sidecar),
+			want:  []string{"/spec/replicas", "/spec/template/spec/containers/0/image"},
+		},
+		{
+			name:  "changed manifest is never ignored",
+			rules: "metadata.annotations",
+			out: applied(genericDeployment(), func(obj client.Object) {
+				obj.GetAnnotations()[HashAnnotation] = "stale"
+			}),
+			want:        nil,
+			wantChanged: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := ParseIgnoreRules(tt.rules)
+			if err != nil {
+				t.Fatalf("ParseIgnoreRules() error = %v", err)
+			}
+
+			report, err := Ignoring(Generic, rules)(genericDeployment(), tt.out)
+			if err != nil {
+				t.Fatalf("Ignoring() error = %v", err)
+			}
+
+			var got []string
+			for _, difference := range report.Differences {
+				got = append(got, difference.Path)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Ignoring() differences = %v, want %v", got, tt.want)
+			}
+			if report.Changed != tt.wantChanged {
+				t.Errorf("Ignoring() changed = %v, want %v", report.Changed, tt.wantChanged)
+			}
+		})
+	}
+}
+
+func TestIgnoring_Deployment(t *testing.T) {
+	in := genericDeployment()
+	out := genericDeployment()
+	out.Spec.Template.Spec.Containers = append(out.Spec.Template.Spec.Containers, corev1.Container{Name: "istio-proxy"})
+
+	rules, _ := ParseIgnoreRules("Deployment=spec.template.spec.containers")
+	if report, err := Ignoring(Deployment, rules)(in, out); err != nil || report.Drifted() {
+		t.Errorf("Ignoring() = %v, %v, want no drift in an ignored field", report, err)
+	}
+}
@@ -0,0 +1,93 @@
+package driftdetection
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// maxValueLength bounds the length of a rendered value in a drift report, so that a report of a large field still fits
+// in an Event and in the status of the Application
+const maxValueLength int = 128
+
+// Difference is a single field that differs between the generated manifest and the object on the cluster
+type Difference struct {
+	// Path is the JSON pointer to the field, such as /spec/replicas
+	Path string `json:"path"`
+
+	// Expected is the JSON encoded value of the field in the generated manifest
+	Expected string `json:"expected"`
+
+	// Actual is the JSON encoded value of the field on the cluster, empty when the field is not set
+	Actual string `json:"actual,omitempty"`
+}
+
+// Report is the structured result of comparing a generated manifest to the object on the cluster
+type Report struct {
+	// Differences lists every field that has drifted
+	Differences []Difference `json:"differences,omitempty"`
+
+	// Changed is set when the object was applied from another manifest than the one generated now, in which case the
+	// differences stem from a change to the Application rather than from drift on the cluster
+	Changed bool `json:"changed,omitempty"`
+}
+
+// Drifted reports if the object on the cluster has drifted from the generated manifest.  The differences of an object
+// whose manifest changed are expected until the new manifest is applied, and are not drift.
+func (r Report) Drifted() bool {
+	return !r.Changed && len(r.Differences) > 0
+}
+
+// String summarises the report on a single line, such as `/spec/replicas: expected 2, actual 3`
+func (r Report) String() string {
+	summaries := []string{}
+	for _, difference := range r.Differences {
+		actual := difference.Actual
+		if actual == "" {
+			actual = "<unset>"
+		}
+		summaries = append(summaries, fmt.Sprintf("%s: expected %s, actual %s", difference.Path, difference.Expected, actual))
+	}
+
+	return strings.Join(summaries, "; ")
+}
+
+// compare adds a difference to the report when the expected and actual values are not deeply equal
+func (r *Report) compare(path string, expected, actual interface{}) {
+	if reflect.DeepEqual(expected, actual) {
+		return
+	}
+
+	r.add(path, expected, actual)
+}
+
+// add records a difference in the report, a nil actual value is recorded as an unset field
+func (r *Report) add(path string, expected, actual interface{}) {
+	difference := Difference{Path: path, Expected: render(expected)}
+	if actual != nil {
+		difference.Actual = render(actual)
+	}
+
+	r.Differences = append(r.Differences, difference)
+}
+
+// render encodes a value as compact JSON, truncated to maxValueLength
+func render(value interface{}) string {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		raw = []byte(fmt.Sprint(value))
+	}
+
+	rendered := string(raw)
+	if len(rendered) > maxValueLength {
+		rendered = rendered[:maxValueLength] + "..."
+	}
+
+	return rendered
+}
+
+// escape encodes a key as a JSON pointer reference token
+func escape(key string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1")
+}
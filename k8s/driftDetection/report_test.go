@@ -0,0 +1,51 @@
+package driftdetection
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReport_String(t *testing.T) {
+	tests := []struct {
+		name   string
+		report Report
+		want   string
+	}{
+		{
+			name:   "no drift",
+			report: Report{},
+			want:   "",
+		},
+		{
+			name: "differences",
+			report: Report{Differences: []Difference{
+				{Path: "/spec/replicas", Expected: "2", Actual: "3"},
+				{Path: "/metadata/labels/app", Expected: `"acme-application"`},
+			}},
+			want: `/spec/replicas: expected 2, actual 3; /metadata/labels/app: expected "acme-application", actual <unset>`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.report.String(); got != tt.want {
+				t.Errorf("Report.String() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReport_Add(t *testing.T) {
+	report := Report{}
+	report.add("/metadata/annotations/"+escape("acme.io/spec-hash"), strings.Repeat("x", 200), nil)
+
+	difference := report.Differences[0]
+	if difference.Path != "/metadata/annotations/acme.io~1spec-hash" {
+		t.Errorf("Report.add() path = %v, want an escaped JSON pointer", difference.Path)
+	}
+	if len(difference.Expected) != maxValueLength+len("...") {
+		t.Errorf("Report.add() expected length = %v, want the value truncated to %v", len(difference.Expected), maxValueLength)
+	}
+	if difference.Actual != "" || !report.Drifted() {
+		t.Errorf("Report.add() = %v, want an unset actual value that counts as drift", difference)
+	}
+}
@@ -0,0 +1,95 @@
+package fieldpath
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Parse splits a field of an object into its reference tokens.  The field is given either as a JSON pointer, such as
+// /metadata/annotations/alb.ingress.kubernetes.io~1scheme, or as a field path, such as
+// metadata.annotations[alb.ingress.kubernetes.io/scheme] or spec.template.spec.containers[*].image.
+func Parse(path string) ([]string, error) {
+	var tokens []string
+	var err error
+	if strings.HasPrefix(path, "/") {
+		tokens, err = ParsePointer(path)
+	} else {
+		tokens, err = parseFieldPath(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid field %q: %w", path, err)
+	}
+
+	return tokens, nil
+}
+
+// Matches reports if the path is the same as, or a parent of, the tokens, a * token in the path matches any single token
+func Matches(path, tokens []string) bool {
+	if len(path) > len(tokens) {
+		return false
+	}
+	for i, token := range path {
+		if token != "*" && token != tokens[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ParsePointer splits a JSON pointer into its unescaped reference tokens
+func ParsePointer(pointer string) ([]string, error) {
+	if pointer == "/" {
+		return []string{}, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("a JSON pointer must start with /")
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		if token == "" {
+			return nil, fmt.Errorf("a JSON pointer must not contain empty reference tokens")
+		}
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+
+	return tokens, nil
+}
+
+// parseFieldPath splits a dotted field path into reference tokens, a key in square brackets is taken as a single token
+// so that it may contain dots and slashes
+func parseFieldPath(path string) ([]string, error) {
+	tokens := []string{}
+	for len(path) > 0 {
+		switch path[0] {
+		case '.':
+			if len(tokens) == 0 || len(path) == 1 || path[1] == '.' || path[1] == '[' {
+				return nil, fmt.Errorf("a field path must not contain empty fields")
+			}
+			path = path[1:]
+		case '[':
+			end := strings.Index(path, "]")
+			if end < 2 {
+				return nil, fmt.Errorf("a field path must close every key with ] and must not contain empty keys")
+			}
+			tokens = append(tokens, path[1:end])
+			path = path[end+1:]
+			if len(path) > 0 && path[0] != '.' && path[0] != '[' {
+				return nil, fmt.Errorf("a field path key must be followed by . or [")
+			}
+		default:
+			end := strings.IndexAny(path, ".[")
+			if end < 0 {
+				end = len(path)
+			}
+			tokens = append(tokens, path[:end])
+			path = path[end:]
+		}
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("a field path must not be empty")
+	}
+
+	return tokens, nil
+}
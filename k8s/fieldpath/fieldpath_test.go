@@ -0,0 +1,119 @@
+package fieldpath
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "json pointer",
+			path: "/metadata/annotations/alb.ingress.kubernetes.io~1scheme",
+			want: []string{"metadata", "annotations", "alb.ingress.kubernetes.io/scheme"},
+		},
+		{
+			name: "json pointer to the root",
+			path: "/",
+			want: []string{},
+		},
+		{
+			name: "field path",
+			path: "spec.template.spec.containers[*].image",
+			want: []string{"spec", "template", "spec", "containers", "*", "image"},
+		},
+		{
+			name: "field path with consecutive keys",
+			path: "metadata.annotations[sidecar.istio.io/status][0]",
+			want: []string{"metadata", "annotations", "sidecar.istio.io/status", "0"},
+		},
+		{
+			name:    "empty",
+			path:    "",
+			wantErr: true,
+		},
+		{
+			name:    "trailing dot",
+			path:    "spec.replicas.",
+			wantErr: true,
+		},
+		{
+			name:    "empty key",
+			path:    "metadata.annotations[]",
+			wantErr: true,
+		},
+		{
+			name:    "text after a key",
+			path:    "metadata.annotations[acme.io/owner]name",
+			wantErr: true,
+		},
+		{
+			name:    "empty reference token",
+			path:    "/spec//replicas",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatches(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   []string
+		tokens []string
+		want   bool
+	}{
+		{
+			name:   "same field",
+			path:   []string{"spec", "replicas"},
+			tokens: []string{"spec", "replicas"},
+			want:   true,
+		},
+		{
+			name:   "parent field",
+			path:   []string{"spec", "template"},
+			tokens: []string{"spec", "template", "spec", "containers", "0", "image"},
+			want:   true,
+		},
+		{
+			name:   "wildcard",
+			path:   []string{"spec", "template", "spec", "containers", "*", "image"},
+			tokens: []string{"spec", "template", "spec", "containers", "1", "image"},
+			want:   true,
+		},
+		{
+			name:   "child field",
+			path:   []string{"spec", "template", "spec"},
+			tokens: []string{"spec", "template"},
+			want:   false,
+		},
+		{
+			name:   "sibling field",
+			path:   []string{"spec", "selector"},
+			tokens: []string{"spec", "replicas"},
+			want:   false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Matches(tt.path, tt.tokens); got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -4,13 +4,17 @@ import (
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	acmeapi "github.com/nathanbrophy/portfolio-demo/k8s/api"
+	acmegdrift "github.com/nathanbrophy/portfolio-demo/k8s/driftDetection"
 	"github.com/nathanbrophy/portfolio-demo/k8s/utils"
 )
 
+//+kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
+
 // DeploymentGeneratorV1 implemented the Generator interface for the deployment k8s manifest type
 type DeploymentGeneratorV1 struct{}
 
@@ -28,6 +32,18 @@ func (d *DeploymentGeneratorV1) Object(in acmeapi.Application) client.Object {
 
 	selectorLabels.MatchLabels = baseLabels
 
+	// The additional labels are only set on the Pod template, as the selector
+	// of a Deployment cannot be changed once it has been created.
+	templateLabels := objectLabels(in)
+	for k, v := range baseLabels {
+		templateLabels[k] = v
+	}
+
+	resources := corev1.ResourceRequirements{}
+	if in.Resources() != nil {
+		resources = *in.Resources()
+	}
+
 	generated := &appsv1.Deployment{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       "Deployment",
@@ -35,7 +51,7 @@ func (d *DeploymentGeneratorV1) Object(in acmeapi.Application) client.Object {
 		},
 		ObjectMeta: metav1.ObjectMeta{
 			Name:   *in.Name(),
-			Labels: labelsGenerator(in),
+			Labels: objectLabels(in),
 		},
 		Spec: appsv1.DeploymentSpec{
 			Replicas: in.Replicas(),
@@ -49,7 +65,7 @@ func (d *DeploymentGeneratorV1) Object(in acmeapi.Application) client.Object {
 			},
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
-					Labels: baseLabels,
+					Labels: templateLabels,
 				},
 				Spec: corev1.PodSpec{
 					Containers: []corev1.Container{
@@ -69,6 +85,7 @@ func (d *DeploymentGeneratorV1) Object(in acmeapi.Application) client.Object {
 								},
 							},
 							Ports:                    generateContainerPorts(in),
+							Resources:                resources,
 							TerminationMessagePath:   "/dev/termination-log",
 							TerminationMessagePolicy: corev1.TerminationMessageReadFile,
 						},
@@ -82,3 +99,13 @@ func (d *DeploymentGeneratorV1) Object(in acmeapi.Application) client.Object {
 
 	return generated
 }
+
+func init() {
+	DefaultRegistry.Register(Registration{
+		GVK:       schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"},
+		Order:     10,
+		Generator: DefaultDeploymentGenerator,
+		Driftor:   acmegdrift.Generic,
+		Type:      &appsv1.Deployment{},
+	})
+}
@@ -46,7 +46,6 @@ func TestDeploymentGeneratorV1_Object(t *testing.T) {
 					Labels: acmetest.DefaultMatchLabels(),
 				},
 				Spec: appsv1.DeploymentSpec{
-					Replicas: func(x int32) *int32 { return &x }(1),
 					Selector: &metav1.LabelSelector{
 						MatchLabels: defaultLabelsSel,
 					},
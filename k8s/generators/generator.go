@@ -9,6 +9,14 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+const (
+	// ManagedByLabel is the label that marks every generated object as managed by the controller
+	ManagedByLabel string = "app.kubernetes.io/managed-by"
+
+	// ManagedBy is the value of the ManagedByLabel on every generated object
+	ManagedBy string = "acme-controller"
+)
+
 var (
 	DefaultDeploymentGenerator     Generator = &DeploymentGeneratorV1{}
 	DefaultServiceGenerator        Generator = &ServiceGeneratorV1{}
@@ -25,12 +33,26 @@ type Generator interface {
 // labelsGenerator will generate a static set of labels for the downstream resouces, this method is idempotent
 func labelsGenerator(in acmeapi.Application) map[string]string {
 	return map[string]string{
-		"app.kubernetes.io/name":       *in.Name(),
-		"app.kubernetes.io/instance":   fmt.Sprintf("%s-%s", *in.Name(), *in.Instancer()),
-		"app.kubernetes.io/version":    *in.Version(),
-		"app.kubernetes.io/managed-by": "acme-controller",
-		"app.kubernetes.io/part-of":    "acme-application",
+		"app.kubernetes.io/name":     *in.Name(),
+		"app.kubernetes.io/instance": fmt.Sprintf("%s-%s", *in.Name(), *in.Instancer()),
+		"app.kubernetes.io/version":  *in.Version(),
+		ManagedByLabel:               ManagedBy,
+		"app.kubernetes.io/part-of":  "acme-application",
+	}
+}
+
+// objectLabels will generate the labels for the metadata of the downstream resources, the additional labels of the
+// Application never replace a label set by labelsGenerator, so that the selectors and the managed-by label still hold
+func objectLabels(in acmeapi.Application) map[string]string {
+	labels := map[string]string{}
+	for k, v := range in.Labels() {
+		labels[k] = v
 	}
+	for k, v := range labelsGenerator(in) {
+		labels[k] = v
+	}
+
+	return labels
 }
 
 // generateAppSelector will generate the app selector for the service and deployment resources
@@ -3,13 +3,14 @@ package generators
 import (
 	networkingv1 "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	acmeapi "github.com/nathanbrophy/portfolio-demo/k8s/api"
-	acmeutils "github.com/nathanbrophy/portfolio-demo/k8s/utils"
+	acmegdrift "github.com/nathanbrophy/portfolio-demo/k8s/driftDetection"
 )
 
-const ingressClass string = "alb"
+//+kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=get;list;watch;create;update;patch;delete
 
 // IngressGeneratorV1 implemented the Generator interface for the service k8s manifest type
 type IngressGeneratorV1 struct{}
@@ -23,15 +24,12 @@ func (s *IngressGeneratorV1) Object(in acmeapi.Application) client.Object {
 			APIVersion: "networking.k8s.io/v1",
 		},
 		ObjectMeta: metav1.ObjectMeta{
-			Name:   *in.Name(),
-			Labels: labelsGenerator(in),
-			Annotations: map[string]string{
-				"alb.ingress.kubernetes.io/scheme":      "internet-facing",
-				"alb.ingress.kubernetes.io/target-type": "ip",
-			},
+			Name:        *in.Name(),
+			Labels:      objectLabels(in),
+			Annotations: in.IngressAnnotations(),
 		},
 		Spec: networkingv1.IngressSpec{
-			IngressClassName: acmeutils.StringPointerGenerator(ingressClass),
+			IngressClassName: in.IngressClassName(),
 			Rules: []networkingv1.IngressRule{
 				{
 					IngressRuleValue: networkingv1.IngressRuleValue{
@@ -59,3 +57,13 @@ func (s *IngressGeneratorV1) Object(in acmeapi.Application) client.Object {
 
 	return generated
 }
+
+func init() {
+	DefaultRegistry.Register(Registration{
+		GVK:       schema.GroupVersionKind{Group: "networking.k8s.io", Version: "v1", Kind: "Ingress"},
+		Order:     40,
+		Generator: DefaultIngressGenerator,
+		Driftor:   acmegdrift.Generic,
+		Type:      &networkingv1.Ingress{},
+	})
+}
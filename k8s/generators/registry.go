@@ -0,0 +1,83 @@
+package generators
+
+import (
+	"fmt"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	acmeapi "github.com/nathanbrophy/portfolio-demo/k8s/api"
+	acmegdrift "github.com/nathanbrophy/portfolio-demo/k8s/driftDetection"
+)
+
+// Registration declares a kind of downstream object that is generated for Applications, along with everything the
+// controller needs to reconcile it
+type Registration struct {
+	// GVK is the group, version and kind of the generated object
+	GVK schema.GroupVersionKind
+
+	// Order places the kind among the registered kinds, which are reconciled from the lowest order to the highest, so
+	// that the order does not depend on the order the files of this package are initialised in
+	Order int
+
+	// Generator renders the object from the Application
+	Generator Generator
+
+	// Applies reports if the object is generated for the Application, it is generated for every Application when nil
+	Applies func(acmeapi.Application) bool
+
+	// Driftor compares the generated object to its cluster state
+	Driftor acmegdrift.DriftDetectionFunc
+
+	// Type is an empty object of the kind, which the controller watches for changes and copies to load the cluster
+	// state into
+	Type client.Object
+}
+
+// AppliesTo reports if the object is generated for the Application
+func (r Registration) AppliesTo(in acmeapi.Application) bool {
+	return r.Applies == nil || r.Applies(in)
+}
+
+// New returns an empty object of the kind to load the cluster state into
+func (r Registration) New() client.Object {
+	return r.Type.DeepCopyObject().(client.Object)
+}
+
+// Registry holds the kinds of downstream objects generated for Applications, in the order they are reconciled.  Kinds
+// of the same order are kept in the order they were registered in.
+type Registry struct {
+	registrations []Registration
+}
+
+// DefaultRegistry holds the kinds generated by this package, each of which registers itself from the file it is
+// generated in
+var DefaultRegistry = &Registry{}
+
+// Register adds a kind to the registry, registering the same kind twice is a programming error and panics
+func (r *Registry) Register(registration Registration) {
+	for _, registered := range r.registrations {
+		if registered.GVK == registration.GVK {
+			panic(fmt.Sprintf("generator for %s is already registered", registration.GVK))
+		}
+	}
+
+	i := sort.Search(len(r.registrations), func(i int) bool { return r.registrations[i].Order > registration.Order })
+	r.registrations = append(r.registrations[:i], append([]Registration{registration}, r.registrations[i:]...)...)
+}
+
+// Registrations returns every registered kind
+func (r *Registry) Registrations() []Registration {
+	return append([]Registration{}, r.registrations...)
+}
+
+// Kinds returns the kind of every registration, in the order they are reconciled
+func (r *Registry) Kinds() []string {
+	kinds := []string{}
+	for _, registration := range r.registrations {
+		kinds = append(kinds, registration.GVK.Kind)
+	}
+
+	return kinds
+}
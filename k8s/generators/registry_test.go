@@ -0,0 +1,115 @@
+package generators
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	acmeapi "github.com/nathanbrophy/portfolio-demo/k8s/api"
+	acmetest "github.com/nathanbrophy/portfolio-demo/k8s/utils/test"
+)
+
+func TestDefaultRegistry(t *testing.T) {
+	want := map[string]bool{"Deployment": true, "Service": true, "ServiceAccount": true, "Ingress": true}
+
+	registrations := DefaultRegistry.Registrations()
+	if len(registrations) != len(want) {
+		t.Fatalf("DefaultRegistry has %d registrations, want %d", len(registrations), len(want))
+	}
+	if got, order := DefaultRegistry.Kinds(), []string{"Deployment", "Service", "ServiceAccount", "Ingress"}; !reflect.DeepEqual(got, order) {
+		t.Errorf("DefaultRegistry kinds = %v, want the order %v", got, order)
+	}
+	for _, registration := range registrations {
+		if !want[registration.GVK.Kind] {
+			t.Errorf("DefaultRegistry registers unexpected kind %s", registration.GVK.Kind)
+		}
+
+		generated := registration.Generator.Object(acmetest.GenerateCRWithDefaults())
+		if got := generated.GetObjectKind().GroupVersionKind(); got != registration.GVK {
+			t.Errorf("generator for %s renders a %s", registration.GVK, got)
+		}
+		if reflect.TypeOf(registration.New()) != reflect.TypeOf(generated) {
+			t.Errorf("registration for %s loads a %T, want a %T", registration.GVK, registration.New(), generated)
+		}
+		if registration.Driftor == nil {
+			t.Errorf("registration for %s has no drift detection", registration.GVK)
+		}
+	}
+}
+
+func TestRegistration_New(t *testing.T) {
+	registration := Registration{Type: &corev1.Service{}}
+
+	loaded := registration.New()
+	loaded.SetName("loaded")
+	if registration.Type.GetName() != "" || registration.New().GetName() != "" {
+		t.Errorf("New() returned the type itself, want a fresh object every time")
+	}
+}
+
+func TestRegistration_AppliesTo(t *testing.T) {
+	tests := []struct {
+		name    string
+		applies func(acmeapi.Application) bool
+		want    bool
+	}{
+		{
+			name: "every Application",
+			want: true,
+		},
+		{
+			name:    "filtered out",
+			applies: func(in acmeapi.Application) bool { return *in.Port() != 8081 },
+			want:    false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registration := Registration{Applies: tt.applies}
+			if got := registration.AppliesTo(acmetest.GenerateCRWithDefaults()); got != tt.want {
+				t.Errorf("AppliesTo() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegistry_Register(t *testing.T) {
+	registry := &Registry{}
+	registration := Registration{
+		GVK:       schema.GroupVersionKind{Version: "v1", Kind: "Service"},
+		Generator: DefaultServiceGenerator,
+		Type:      &corev1.Service{},
+	}
+	registry.Register(registration)
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Register() of a kind that is already registered did not panic")
+		}
+	}()
+	registry.Register(registration)
+}
+
+func TestRegistry_Kinds(t *testing.T) {
+	registry := &Registry{}
+	registry.Register(Registration{GVK: schema.GroupVersionKind{Version: "v1", Kind: "Service"}})
+	registry.Register(Registration{GVK: schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}})
+
+	if got, want := registry.Kinds(), []string{"Service", "Deployment"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Registry.Kinds() = %v, want %v", got, want)
+	}
+}
+
+func TestRegistry_Order(t *testing.T) {
+	registry := &Registry{}
+	registry.Register(Registration{GVK: schema.GroupVersionKind{Group: "networking.k8s.io", Version: "v1", Kind: "Ingress"}, Order: 40})
+	registry.Register(Registration{GVK: schema.GroupVersionKind{Version: "v1", Kind: "Service"}, Order: 20})
+	registry.Register(Registration{GVK: schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, Order: 20})
+	registry.Register(Registration{GVK: schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}, Order: 10})
+
+	if got, want := registry.Kinds(), []string{"Deployment", "Service", "ConfigMap", "Ingress"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Registry.Kinds() = %v, want %v", got, want)
+	}
+}
@@ -3,12 +3,16 @@ package generators
 import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	acmeapi "github.com/nathanbrophy/portfolio-demo/k8s/api"
+	acmegdrift "github.com/nathanbrophy/portfolio-demo/k8s/driftDetection"
 )
 
+//+kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch;create;update;patch;delete
+
 // ServiceGeneratorV1 implemented the Generator interface for the service k8s manifest type
 type ServiceGeneratorV1 struct{}
 
@@ -21,7 +25,7 @@ func (s *ServiceGeneratorV1) Object(in acmeapi.Application) client.Object {
 		},
 		ObjectMeta: metav1.ObjectMeta{
 			Name:   *in.Name(),
-			Labels: labelsGenerator(in),
+			Labels: objectLabels(in),
 		},
 		Spec: corev1.ServiceSpec{
 			Selector: generateAppSelector(in).MatchLabels,
@@ -37,3 +41,13 @@ func (s *ServiceGeneratorV1) Object(in acmeapi.Application) client.Object {
 
 	return generated
 }
+
+func init() {
+	DefaultRegistry.Register(Registration{
+		GVK:       schema.GroupVersionKind{Group: "", Version: "v1", Kind: "Service"},
+		Order:     20,
+		Generator: DefaultServiceGenerator,
+		Driftor:   acmegdrift.Generic,
+		Type:      &corev1.Service{},
+	})
+}
@@ -3,11 +3,15 @@ package generators
 import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	acmeapi "github.com/nathanbrophy/portfolio-demo/k8s/api"
+	acmegdrift "github.com/nathanbrophy/portfolio-demo/k8s/driftDetection"
 )
 
+//+kubebuilder:rbac:groups="",resources=serviceaccounts,verbs=get;list;watch;create;update;patch;delete
+
 // ServiceAccountGeneratorV1 implemented the Generator interface for the service account k8s manifest type
 type ServiceAccountGeneratorV1 struct{}
 
@@ -28,10 +32,20 @@ func (s *ServiceAccountGeneratorV1) Object(in acmeapi.Application) client.Object
 		},
 		ObjectMeta: metav1.ObjectMeta{
 			Name:   *in.ServiceAccount(),
-			Labels: labelsGenerator(in),
+			Labels: objectLabels(in),
 		},
 		ImagePullSecrets: lors,
 	}
 
 	return generated
 }
+
+func init() {
+	DefaultRegistry.Register(Registration{
+		GVK:       schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ServiceAccount"},
+		Order:     30,
+		Generator: DefaultServiceAccountGenerator,
+		Driftor:   acmegdrift.Generic,
+		Type:      &corev1.ServiceAccount{},
+	})
+}
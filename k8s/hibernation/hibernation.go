@@ -0,0 +1,60 @@
+package hibernation
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Window is a recurring hibernation window, opened by the sleep schedule and closed by the wake schedule
+type Window struct {
+	sleep    cron.Schedule
+	wake     cron.Schedule
+	location *time.Location
+}
+
+// Parse builds a hibernation window from two standard five field cron schedules, evaluated in the given IANA
+// time zone, where an empty time zone is UTC.  Schedules that never fire are rejected.
+func Parse(sleep, wake, timeZone string) (*Window, error) {
+	location, err := time.LoadLocation(timeZone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid hibernation time zone %q: %w", timeZone, err)
+	}
+
+	sleepSchedule, err := cron.ParseStandard(sleep)
+	if err != nil {
+		return nil, fmt.Errorf("invalid hibernation sleep schedule %q: %w", sleep, err)
+	}
+
+	wakeSchedule, err := cron.ParseStandard(wake)
+	if err != nil {
+		return nil, fmt.Errorf("invalid hibernation wake schedule %q: %w", wake, err)
+	}
+
+	// A schedule that matches no date, such as February 30th, has no next time, which would leave the
+	// Application asleep, or awake, for good
+	now := time.Now().In(location)
+	if sleepSchedule.Next(now).IsZero() {
+		return nil, fmt.Errorf("hibernation sleep schedule %q never fires", sleep)
+	}
+	if wakeSchedule.Next(now).IsZero() {
+		return nil, fmt.Errorf("hibernation wake schedule %q never fires", wake)
+	}
+
+	return &Window{sleep: sleepSchedule, wake: wakeSchedule, location: location}, nil
+}
+
+// State reports if the Application is asleep at the given time, along with the time of the next transition.
+// The Application is asleep when the next wake up comes before the next time it is put to sleep.
+func (w *Window) State(now time.Time) (bool, time.Time) {
+	now = now.In(w.location)
+	nextSleep := w.sleep.Next(now)
+	nextWake := w.wake.Next(now)
+
+	if nextWake.Before(nextSleep) {
+		return true, nextWake
+	}
+
+	return false, nextSleep
+}
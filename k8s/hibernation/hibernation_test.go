@@ -0,0 +1,128 @@
+package hibernation
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		sleep    string
+		wake     string
+		timeZone string
+		wantErr  bool
+	}{
+		{
+			name:  "utc by default",
+			sleep: "0 20 * * 1-5",
+			wake:  "0 7 * * 1-5",
+		},
+		{
+			name:     "time zone",
+			sleep:    "0 20 * * 1-5",
+			wake:     "0 7 * * 1-5",
+			timeZone: "Europe/Berlin",
+		},
+		{
+			name:     "unknown time zone",
+			sleep:    "0 20 * * 1-5",
+			wake:     "0 7 * * 1-5",
+			timeZone: "Mars/Olympus_Mons",
+			wantErr:  true,
+		},
+		{
+			name:    "invalid sleep schedule",
+			sleep:   "at night",
+			wake:    "0 7 * * 1-5",
+			wantErr: true,
+		},
+		{
+			name:    "invalid wake schedule",
+			sleep:   "0 20 * * 1-5",
+			wake:    "0 7 * *",
+			wantErr: true,
+		},
+		{
+			name:    "sleep schedule that never fires",
+			sleep:   "0 20 30 2 *",
+			wake:    "0 7 * * 1-5",
+			wantErr: true,
+		},
+		{
+			name:    "wake schedule that never fires",
+			sleep:   "0 20 * * 1-5",
+			wake:    "0 7 31 4 *",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(tt.sleep, tt.wake, tt.timeZone); (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWindow_State(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatalf("time.LoadLocation() error = %v", err)
+	}
+
+	// Weekdays from 07:00 to 20:00 Berlin time, asleep overnight and on the weekend
+	window, err := Parse("0 20 * * 1-5", "0 7 * * 1-5", "Europe/Berlin")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		now        time.Time
+		wantAsleep bool
+		wantNext   time.Time
+	}{
+		{
+			name:       "awake during the working day",
+			now:        time.Date(2023, time.June, 14, 12, 0, 0, 0, berlin),
+			wantAsleep: false,
+			wantNext:   time.Date(2023, time.June, 14, 20, 0, 0, 0, berlin),
+		},
+		{
+			name:       "asleep overnight",
+			now:        time.Date(2023, time.June, 14, 23, 0, 0, 0, berlin),
+			wantAsleep: true,
+			wantNext:   time.Date(2023, time.June, 15, 7, 0, 0, 0, berlin),
+		},
+		{
+			name:       "asleep over the weekend",
+			now:        time.Date(2023, time.June, 17, 12, 0, 0, 0, berlin),
+			wantAsleep: true,
+			wantNext:   time.Date(2023, time.June, 19, 7, 0, 0, 0, berlin),
+		},
+		{
+			name:       "evaluated in the window time zone",
+			now:        time.Date(2023, time.June, 14, 5, 30, 0, 0, time.UTC),
+			wantAsleep: false,
+			wantNext:   time.Date(2023, time.June, 14, 20, 0, 0, 0, berlin),
+		},
+		{
+			name:       "awake at the moment of waking up",
+			now:        time.Date(2023, time.June, 14, 7, 0, 0, 0, berlin),
+			wantAsleep: false,
+			wantNext:   time.Date(2023, time.June, 14, 20, 0, 0, 0, berlin),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			asleep, next := window.State(tt.now)
+			if asleep != tt.wantAsleep {
+				t.Errorf("Window.State() asleep = %v, want %v", asleep, tt.wantAsleep)
+			}
+			if !next.Equal(tt.wantNext) {
+				t.Errorf("Window.State() next = %v, want %v", next, tt.wantNext)
+			}
+		})
+	}
+}
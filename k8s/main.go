@@ -17,22 +17,40 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"flag"
+	"fmt"
 	"os"
+	"strings"
+	"time"
+
+	// Embed the IANA time zone database, the distroless base image does not ship
+	// one and hibernation windows are evaluated in their own time zone.
+	_ "time/tzdata"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
 	acmeiov1beta1 "github.com/nathanbrophy/portfolio-demo/k8s/api/v1beta1"
 	"github.com/nathanbrophy/portfolio-demo/k8s/controllers"
+	acmegdrift "github.com/nathanbrophy/portfolio-demo/k8s/driftDetection"
+	acmegenerators "github.com/nathanbrophy/portfolio-demo/k8s/generators"
+	acmeoperatorconfig "github.com/nathanbrophy/portfolio-demo/k8s/operatorconfig"
+	acmepolicy "github.com/nathanbrophy/portfolio-demo/k8s/policy"
+	acmeregistry "github.com/nathanbrophy/portfolio-demo/k8s/registry"
+	acmetracing "github.com/nathanbrophy/portfolio-demo/k8s/tracing"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -52,11 +70,78 @@ func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var insecureRegistries string
+	var allowedRegistries string
+	var deniedTags string
+	var requireDigestOrSemver bool
+	var registryMirrors string
+	var registryMirrorPullSecret string
+	var registryMirrorsConfigMap string
+	var resyncPeriod time.Duration
+	var driftIgnore string
+	var tracingExporter string
+	var tracingEndpoint string
+	var tracingInsecure bool
+	var tracingSampleRatio float64
+	var backoffBaseDelay time.Duration
+	var backoffMaxDelay time.Duration
+	var maxConcurrentReconciles int
+	var watchNamespaces string
+	var leaseDuration time.Duration
+	var renewDeadline time.Duration
+	var retryPeriod time.Duration
+	var configFile string
+	var configMap string
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.StringVar(&insecureRegistries, "insecure-registries", "",
+		"Comma separated list of registry hosts that are served over plain HTTP when resolving image digests.")
+	flag.StringVar(&allowedRegistries, "image-allowed-registries", "",
+		"Comma separated list of registry and repository prefixes Application images may be pulled from, any registry is allowed when empty.")
+	flag.StringVar(&deniedTags, "image-denied-tags", "",
+		"Comma separated list of image tags that Applications may never deploy, such as latest.")
+	flag.BoolVar(&requireDigestOrSemver, "image-require-digest-or-semver", false,
+		"Require Application images to be pinned to a digest or tagged with a full semantic version.")
+	flag.StringVar(&registryMirrors, "registry-mirrors", "",
+		"Comma separated list of source=mirror rules that rewrite Application images to the registry mirror they are pulled through.")
+	flag.StringVar(&registryMirrorPullSecret, "registry-mirror-pull-secret", "",
+		"Name of the image pull secret for the mirrors given by --registry-mirrors, bound to the generated service accounts.")
+	flag.StringVar(&registryMirrorsConfigMap, "registry-mirrors-configmap", "",
+		"Namespace and name of a ConfigMap, as namespace/name, holding registry mirror rules under the "+acmeregistry.MirrorsConfigMapKey+" key. "+
+			"The ConfigMap is only read on start up, so a change needs a restart of the manager, while the mirrors of the operator config are reloaded.")
+	flag.DurationVar(&resyncPeriod, "resync-period", 10*time.Minute,
+		"The longest an Application goes without being reconciled, so that out of band changes that raise no event are corrected, 0 disables it.")
+	flag.StringVar(&driftIgnore, "drift-ignore", "",
+		"Comma separated list of fields, as JSON pointers or field paths optionally prefixed with Kind=, that are left out when looking for drift on every Application.")
+	flag.StringVar(&tracingExporter, "tracing-exporter", acmetracing.ExporterNone,
+		"The exporter reconciliation traces are sent to, one of none, otlp or stdout.")
+	flag.StringVar(&tracingEndpoint, "tracing-otlp-endpoint", "",
+		"The host and port of the OpenTelemetry collector traces are sent to over OTLP/gRPC, defaults to OTEL_EXPORTER_OTLP_ENDPOINT or localhost:4317.")
+	flag.BoolVar(&tracingInsecure, "tracing-otlp-insecure", false,
+		"Send traces to the OpenTelemetry collector without TLS.")
+	flag.Float64Var(&tracingSampleRatio, "tracing-sample-ratio", 1,
+		"The fraction of reconciliations that are traced, between 0 and 1.")
+	flag.DurationVar(&backoffBaseDelay, "backoff-base-delay", 5*time.Second,
+		"The wait before retrying a failed reconciliation of an Application, doubled on every consecutive failure.")
+	flag.DurationVar(&backoffMaxDelay, "backoff-max-delay", 5*time.Minute,
+		"The longest wait before retrying a failed reconciliation of an Application.")
+	flag.StringVar(&configFile, "config", "",
+		"Path of the operator config YAML file, which is reloaded whenever it changes.")
+	flag.StringVar(&configMap, "config-configmap", "",
+		"Namespace and name of a ConfigMap, as namespace/name, holding the operator config under the "+acmeoperatorconfig.ConfigMapKey+" key, which is reloaded whenever it changes.")
+	flag.IntVar(&maxConcurrentReconciles, "max-concurrent-reconciles", 1,
+		"The number of Applications that are reconciled at the same time.")
+	flag.StringVar(&watchNamespaces, "watch-namespaces", os.Getenv("WATCH_NAMESPACE"),
+		"Comma separated list of namespaces the manager watches, every namespace is watched when empty. Defaults to the WATCH_NAMESPACE environment variable.")
+	flag.DurationVar(&leaseDuration, "leader-elect-lease-duration", 15*time.Second,
+		"The duration that non-leader candidates wait before attempting to acquire leadership.")
+	flag.DurationVar(&renewDeadline, "leader-elect-renew-deadline", 10*time.Second,
+		"The duration that the acting leader retries refreshing leadership before giving it up.")
+	flag.DurationVar(&retryPeriod, "leader-elect-retry-period", 2*time.Second,
+		"The duration leader election clients wait between tries of actions.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -65,6 +150,18 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	shutdownTracing, err := acmetracing.Setup(context.Background(), acmetracing.Options{
+		Exporter:    tracingExporter,
+		Endpoint:    tracingEndpoint,
+		Insecure:    tracingInsecure,
+		SampleRatio: tracingSampleRatio,
+		ServiceName: "acme-controller-manager",
+	})
+	if err != nil {
+		setupLog.Error(err, "unable to set up tracing")
+		os.Exit(1)
+	}
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme:                 scheme,
 		MetricsBindAddress:     metricsAddr,
@@ -72,6 +169,23 @@ func main() {
 		HealthProbeBindAddress: probeAddr,
 		LeaderElection:         enableLeaderElection,
 		LeaderElectionID:       "dace9822.acme.io",
+		LeaseDuration:          &leaseDuration,
+		RenewDeadline:          &renewDeadline,
+		RetryPeriod:            &retryPeriod,
+		// Only the downstream objects managed by the controller are cached, in
+		// the watched namespaces, rather than every object of the same kinds.
+		Cache: cache.Options{
+			Namespaces: splitList(watchNamespaces),
+			ByObject:   controllers.CacheByObject(acmegenerators.DefaultRegistry),
+		},
+		// Image pull secrets are only read when resolving image digests, so they
+		// are read straight from the API server rather than caching every
+		// secret in the cluster in the manager.
+		Client: client.Options{
+			Cache: &client.CacheOptions{
+				DisableFor: []client.Object{&corev1.Secret{}},
+			},
+		},
 		// LeaderElectionReleaseOnCancel defines if the leader should step down voluntarily
 		// when the Manager ends. This requires the binary to immediately end when the
 		// Manager is stopped, otherwise, this setting is unsafe. Setting this significantly
@@ -89,13 +203,71 @@ func main() {
 		os.Exit(1)
 	}
 
+	imagePolicy := &acmepolicy.ImagePolicy{
+		AllowedRegistries:     splitList(allowedRegistries),
+		RequireDigestOrSemver: requireDigestOrSemver,
+		DeniedTags:            splitList(deniedTags),
+	}
+
+	mirrors, err := loadMirrors(mgr.GetAPIReader(), registryMirrorsConfigMap, registryMirrors, registryMirrorPullSecret)
+	if err != nil {
+		setupLog.Error(err, "unable to load the registry mirror rules")
+		os.Exit(1)
+	}
+
+	if backoffBaseDelay <= 0 || backoffMaxDelay < backoffBaseDelay {
+		setupLog.Error(fmt.Errorf("the base delay %s must be positive and no longer than the max delay %s", backoffBaseDelay, backoffMaxDelay), "invalid reconciliation backoff")
+		os.Exit(1)
+	}
+
+	driftIgnoreRules, err := acmegdrift.ParseIgnoreRules(driftIgnore)
+	if err != nil {
+		setupLog.Error(err, "unable to parse the drift ignore rules")
+		os.Exit(1)
+	}
+
+	// The operator config adds to the image policy, drift ignore rules and
+	// registry mirrors given by the flags, and is loaded up front so that an
+	// invalid config fails the start of the manager rather than being ignored.
+	operatorConfig := acmeoperatorconfig.NewStore(acmeoperatorconfig.FromFlags(*imagePolicy, driftIgnoreRules, mirrors))
+	if err := watchOperatorConfig(mgr, operatorConfig, configFile, configMap); err != nil {
+		setupLog.Error(err, "unable to load the operator config")
+		os.Exit(1)
+	}
+
 	if err = (&controllers.ApplicationReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:    mgr.GetClient(),
+		Scheme:    mgr.GetScheme(),
+		Recorder:  mgr.GetEventRecorderFor("application-controller"),
+		APIReader: mgr.GetAPIReader(),
+		Resolver: &acmeregistry.HTTPResolver{
+			InsecureRegistries: splitList(insecureRegistries),
+		},
+		Generators:              acmegenerators.DefaultRegistry,
+		Config:                  operatorConfig,
+		ResyncPeriod:            resyncPeriod,
+		MaxConcurrentReconciles: maxConcurrentReconciles,
+		Backoff: controllers.Backoff{
+			BaseDelay: backoffBaseDelay,
+			MaxDelay:  backoffMaxDelay,
+		},
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Application")
 		os.Exit(1)
 	}
+	// The admission webhooks are opt in, as the webhook server cannot start
+	// without serving certificates, which are only mounted by config/default.
+	if os.Getenv("ENABLE_WEBHOOKS") == "true" {
+		validator := &acmeiov1beta1.ApplicationValidator{
+			ImagePolicy:    operatorConfig,
+			ExtraResources: operatorConfig,
+			Kinds:          acmegenerators.DefaultRegistry.Kinds(),
+		}
+		if err = (&acmeiov1beta1.Application{}).SetupWebhookWithManager(mgr, validator); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Application")
+			os.Exit(1)
+		}
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -112,4 +284,86 @@ func main() {
 		setupLog.Error(err, "problem running manager")
 		os.Exit(1)
 	}
+
+	// The spans of the last reconciliations are still buffered by the exporter
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdownTracing(ctx); err != nil {
+		setupLog.Error(err, "unable to flush the remaining traces")
+	}
+}
+
+// watchOperatorConfig loads the operator config from the file or the ConfigMap, when either is given, and reloads it
+// whenever it changes
+func watchOperatorConfig(mgr ctrl.Manager, store *acmeoperatorconfig.Store, path, configMap string) error {
+	switch {
+	case path != "" && configMap != "":
+		return fmt.Errorf("the operator config is read from either a file or a ConfigMap, not both")
+	case path != "":
+		config, err := acmeoperatorconfig.Load(path)
+		if err != nil {
+			return err
+		}
+		store.Set(config)
+		return mgr.Add(&acmeoperatorconfig.FileWatcher{Path: path, Store: store})
+	case configMap != "":
+		namespace, name, found := strings.Cut(configMap, "/")
+		if !found {
+			return fmt.Errorf("operator config ConfigMap %q is not of the form namespace/name", configMap)
+		}
+		cm := &corev1.ConfigMap{}
+		if err := mgr.GetAPIReader().Get(context.Background(), types.NamespacedName{Namespace: namespace, Name: name}, cm); err != nil {
+			return err
+		}
+		config, err := acmeoperatorconfig.FromConfigMap(cm)
+		if err != nil {
+			return err
+		}
+		store.Set(config)
+		return acmeoperatorconfig.WatchConfigMap(mgr, namespace, name, store)
+	}
+
+	return nil
+}
+
+// splitList splits a comma separated flag value, dropping empty entries
+func splitList(in string) []string {
+	out := []string{}
+	for _, item := range strings.Split(in, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+
+	return out
+}
+
+// loadMirrors reads the registry mirror rules from the ConfigMap, when one is given, followed by the
+// rules given on the command line.  The ConfigMap is only read on start up.
+func loadMirrors(reader client.Reader, configMap, rules, pullSecret string) (acmeregistry.Mirrors, error) {
+	mirrors := acmeregistry.Mirrors{}
+
+	if configMap != "" {
+		namespace, name, found := strings.Cut(configMap, "/")
+		if !found {
+			return nil, fmt.Errorf("registry mirrors ConfigMap %q is not of the form namespace/name", configMap)
+		}
+
+		cm := &corev1.ConfigMap{}
+		if err := reader.Get(context.Background(), types.NamespacedName{Namespace: namespace, Name: name}, cm); err != nil {
+			return nil, err
+		}
+		fromConfigMap, err := acmeregistry.MirrorsFromConfigMap(cm)
+		if err != nil {
+			return nil, err
+		}
+		mirrors = append(mirrors, fromConfigMap...)
+	}
+
+	parsed, err := acmeregistry.ParseMirrors(rules, pullSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(mirrors, parsed...), nil
 }
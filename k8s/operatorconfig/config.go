@@ -0,0 +1,227 @@
+package operatorconfig
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/yaml"
+
+	acmegdrift "github.com/nathanbrophy/portfolio-demo/k8s/driftDetection"
+	acmepolicy "github.com/nathanbrophy/portfolio-demo/k8s/policy"
+	acmeregistry "github.com/nathanbrophy/portfolio-demo/k8s/registry"
+)
+
+// ConfigMapKey is the key of the ConfigMap holding the operator config as YAML
+const ConfigMapKey string = "config.yaml"
+
+// Config is the operator wide configuration, which applies to every Application
+type Config struct {
+	// Defaults are the settings of the generated objects that an Application does not set itself
+	Defaults Defaults `json:"defaults,omitempty"`
+
+	// ImagePolicy is the policy every Application image must satisfy
+	ImagePolicy acmepolicy.ImagePolicy `json:"imagePolicy,omitempty"`
+
+	// DriftIgnore lists the fields that are left out when looking for drift on every Application
+	DriftIgnore []DriftIgnoreRule `json:"driftIgnore,omitempty"`
+
+	// ExtraResources is the allowlist of the kinds an Application may embed as extra resources
+	ExtraResources acmepolicy.ExtraResourcePolicy `json:"extraResources,omitempty"`
+
+	// Mirrors are the registry mirror rules, which are tried before the rules given by the flags
+	Mirrors acmeregistry.Mirrors `json:"mirrors,omitempty"`
+
+	// ignoreRules are the parsed DriftIgnore rules
+	ignoreRules acmegdrift.IgnoreRules
+}
+
+// Defaults are the settings of the generated objects that an Application does not set itself
+type Defaults struct {
+	// Resources are the compute resources of the Application container
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// Labels are added to every generated object, they never replace a label the controller sets itself
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Ingress configures the generated Ingress
+	Ingress IngressDefaults `json:"ingress,omitempty"`
+}
+
+// IngressDefaults configure the generated Ingress for the ingress controller of the cluster
+type IngressDefaults struct {
+	// ClassName is the class of the ingress controller that serves the Applications
+	ClassName *string `json:"className,omitempty"`
+
+	// Annotations replace the default annotations of the generated Ingress when set
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// DriftIgnoreRule is a list of fields that are left out when looking for drift, on objects of the given kind, or on
+// every object when no kind is given
+type DriftIgnoreRule struct {
+	Kind  string   `json:"kind,omitempty"`
+	Paths []string `json:"paths"`
+}
+
+// FromFlags builds the config given by the flags of the manager, which the loaded config is added to
+func FromFlags(imagePolicy acmepolicy.ImagePolicy, ignoreRules acmegdrift.IgnoreRules, mirrors acmeregistry.Mirrors) *Config {
+	return &Config{ImagePolicy: imagePolicy, ignoreRules: ignoreRules, Mirrors: mirrors}
+}
+
+// Parse decodes and validates the operator config from YAML, unknown fields are rejected so that a typo is not
+// silently ignored
+func Parse(data []byte) (*Config, error) {
+	config := &Config{}
+	if err := yaml.UnmarshalStrict(data, config); err != nil {
+		return nil, fmt.Errorf("cannot decode the operator config: %w", err)
+	}
+
+	for key, value := range config.Defaults.Labels {
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid default label %q: %s", key, strings.Join(errs, "; "))
+		}
+		if errs := validation.IsValidLabelValue(value); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid value %q for default label %q: %s", value, key, strings.Join(errs, "; "))
+		}
+	}
+	for key := range config.Defaults.Ingress.Annotations {
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid default ingress annotation %q: %s", key, strings.Join(errs, "; "))
+		}
+	}
+
+	for _, kind := range config.ExtraResources.AllowedKinds {
+		if schema.ParseGroupKind(kind).Kind == "" {
+			return nil, fmt.Errorf("invalid extra resource kind %q, expected Kind or Kind.group", kind)
+		}
+	}
+
+	for _, m := range config.Mirrors {
+		if m.Source == "" || m.Mirror == "" {
+			return nil, fmt.Errorf("mirror rule %+v needs both a source and a mirror", m)
+		}
+	}
+
+	for _, ignore := range config.DriftIgnore {
+		if len(ignore.Paths) == 0 {
+			return nil, fmt.Errorf("drift ignore rule for kind %q has no paths", ignore.Kind)
+		}
+		for _, path := range ignore.Paths {
+			rule, err := acmegdrift.ParseIgnoreRule(ignore.Kind, path)
+			if err != nil {
+				return nil, err
+			}
+			config.ignoreRules = append(config.ignoreRules, rule)
+		}
+	}
+
+	return config, nil
+}
+
+// Load reads the operator config from a YAML file
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return config, nil
+}
+
+// FromConfigMap reads the operator config from the config.yaml key of a ConfigMap
+func FromConfigMap(cm *corev1.ConfigMap) (*Config, error) {
+	config, err := Parse([]byte(cm.Data[ConfigMapKey]))
+	if err != nil {
+		return nil, fmt.Errorf("ConfigMap %s/%s: %w", cm.Namespace, cm.Name, err)
+	}
+
+	return config, nil
+}
+
+// IgnoreRules returns the fields that are left out when looking for drift
+func (c *Config) IgnoreRules() acmegdrift.IgnoreRules {
+	return c.ignoreRules
+}
+
+// merge adds the loaded config to the config given by the flags.  The image policies, drift ignore rules and extra
+// resource kinds of both apply, and the defaults only come from the loaded config.  The mirror rules of the loaded
+// config come first, so that they win over the rules of the flags for the same source.
+func merge(base, loaded *Config) *Config {
+	if base == nil {
+		base = &Config{}
+	}
+	if loaded == nil {
+		loaded = &Config{}
+	}
+
+	return &Config{
+		Defaults: loaded.Defaults,
+		ImagePolicy: acmepolicy.ImagePolicy{
+			AllowedRegistries:     append(append([]string{}, base.ImagePolicy.AllowedRegistries...), loaded.ImagePolicy.AllowedRegistries...),
+			RequireDigestOrSemver: base.ImagePolicy.RequireDigestOrSemver || loaded.ImagePolicy.RequireDigestOrSemver,
+			DeniedTags:            append(append([]string{}, base.ImagePolicy.DeniedTags...), loaded.ImagePolicy.DeniedTags...),
+		},
+		DriftIgnore: append(append([]DriftIgnoreRule{}, base.DriftIgnore...), loaded.DriftIgnore...),
+		ignoreRules: append(append(acmegdrift.IgnoreRules{}, base.ignoreRules...), loaded.ignoreRules...),
+		ExtraResources: acmepolicy.ExtraResourcePolicy{
+			AllowedKinds: append(append([]string{}, base.ExtraResources.AllowedKinds...), loaded.ExtraResources.AllowedKinds...),
+		},
+		Mirrors: append(append(acmeregistry.Mirrors{}, loaded.Mirrors...), base.Mirrors...),
+	}
+}
+
+// Store holds the config in force, which is replaced whenever the operator config is reloaded.  A reconciliation
+// reads the config once, so that it works with the same config from start to finish.
+type Store struct {
+	base *Config
+
+	mu      sync.RWMutex
+	current *Config
+}
+
+// NewStore creates a store for the config given by the flags, until an operator config is loaded
+func NewStore(base *Config) *Store {
+	return &Store{base: base, current: merge(base, nil)}
+}
+
+// Get returns the config in force, which must not be changed.  A nil store holds an empty config.
+func (s *Store) Get() *Config {
+	if s == nil {
+		return &Config{}
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.current
+}
+
+// Set replaces the loaded operator config
+func (s *Store) Set(loaded *Config) {
+	merged := merge(s.base, loaded)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.current = merged
+}
+
+// ImagePolicy returns the image policy in force, so that the store is an image policy source
+func (s *Store) ImagePolicy() *acmepolicy.ImagePolicy {
+	return &s.Get().ImagePolicy
+}
+
+// ExtraResourcePolicy returns the extra resource policy in force, so that the store is an extra resource source
+func (s *Store) ExtraResourcePolicy() *acmepolicy.ExtraResourcePolicy {
+	return &s.Get().ExtraResources
+}
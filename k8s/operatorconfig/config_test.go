@@ -0,0 +1,172 @@
+package operatorconfig
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	acmegdrift "github.com/nathanbrophy/portfolio-demo/k8s/driftDetection"
+	acmepolicy "github.com/nathanbrophy/portfolio-demo/k8s/policy"
+	acmeregistry "github.com/nathanbrophy/portfolio-demo/k8s/registry"
+)
+
+func TestParse(t *testing.T) {
+	full := `
+defaults:
+  resources:
+    requests:
+      cpu: 100m
+  labels:
+    team: payments
+  ingress:
+    className: nginx
+    annotations:
+      nginx.ingress.kubernetes.io/ssl-redirect: "true"
+imagePolicy:
+  allowedRegistries:
+  - quay.io/acme
+  deniedTags:
+  - latest
+driftIgnore:
+- kind: Deployment
+  paths:
+  - spec.template.spec.containers[*].resources
+- paths:
+  - /metadata/annotations/sidecar.istio.io~1status
+extraResources:
+  allowedKinds:
+  - ConfigMap
+  - CronJob.batch
+`
+
+	tests := []struct {
+		name    string
+		in      string
+		wantErr bool
+	}{
+		{
+			name: "empty",
+			in:   "",
+		},
+		{
+			name: "full",
+			in:   full,
+		},
+		{
+			name:    "unknown field",
+			in:      "defaults:\n  ingressClass: nginx\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid label",
+			in:      "defaults:\n  labels:\n    team: not a valid value\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid annotation",
+			in:      "defaults:\n  ingress:\n    annotations:\n      not a key: value\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid drift ignore path",
+			in:      "driftIgnore:\n- paths:\n  - spec..replicas\n",
+			wantErr: true,
+		},
+		{
+			name:    "drift ignore rule without paths",
+			in:      "driftIgnore:\n- kind: Service\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid extra resource kind",
+			in:      "extraResources:\n  allowedKinds:\n  - .batch\n",
+			wantErr: true,
+		},
+		{
+			name:    "mirror rule without a mirror",
+			in:      "mirrors:\n- source: docker.io\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.in))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	config, err := Parse([]byte(full))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if got := config.Defaults.Resources.Requests[corev1.ResourceCPU]; !got.Equal(resource.MustParse("100m")) {
+		t.Errorf("Parse() cpu request = %v, want 100m", got.String())
+	}
+	wantRules := acmegdrift.IgnoreRules{
+		{Kind: "Deployment", Path: []string{"spec", "template", "spec", "containers", "*", "resources"}},
+		{Path: []string{"metadata", "annotations", "sidecar.istio.io/status"}},
+	}
+	if !reflect.DeepEqual(config.IgnoreRules(), wantRules) {
+		t.Errorf("Parse() ignore rules = %v, want %v", config.IgnoreRules(), wantRules)
+	}
+	if err := config.ExtraResources.Validate(schema.GroupKind{Group: "batch", Kind: "CronJob"}); err != nil {
+		t.Errorf("Parse() extra resource policy does not allow CronJob.batch: %v", err)
+	}
+}
+
+func TestStore(t *testing.T) {
+	fromFlags := FromFlags(
+		acmepolicy.ImagePolicy{AllowedRegistries: []string{"quay.io/acme"}},
+		acmegdrift.IgnoreRules{{Kind: "Service", Path: []string{"spec", "clusterIP"}}},
+		acmeregistry.Mirrors{{Source: "docker.io", Mirror: "mirror.acme.internal/docker-hub"}},
+	)
+	store := NewStore(fromFlags)
+
+	if got := store.ImagePolicy().AllowedRegistries; !reflect.DeepEqual(got, []string{"quay.io/acme"}) {
+		t.Errorf("ImagePolicy() before a load = %v, want the flags only", got)
+	}
+
+	loaded, err := Parse([]byte("imagePolicy:\n  allowedRegistries: [ghcr.io/acme]\n  requireDigestOrSemver: true\ndriftIgnore:\n- paths: [/spec/replicas]\ndefaults:\n  labels:\n    team: payments\nmirrors:\n- source: docker.io\n  mirror: mirror.acme.internal/hub\n  pullSecret: hub-pull\n"))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	store.Set(loaded)
+
+	if err := store.ExtraResourcePolicy().Validate(schema.GroupKind{Kind: "ConfigMap"}); err == nil {
+		t.Errorf("ExtraResourcePolicy() allows ConfigMap, want no kind allowed by a config without extraResources")
+	}
+
+	config := store.Get()
+	wantPolicy := acmepolicy.ImagePolicy{AllowedRegistries: []string{"quay.io/acme", "ghcr.io/acme"}, RequireDigestOrSemver: true, DeniedTags: []string{}}
+	if !reflect.DeepEqual(config.ImagePolicy, wantPolicy) {
+		t.Errorf("Get() image policy = %v, want %v", config.ImagePolicy, wantPolicy)
+	}
+	if got := len(config.IgnoreRules()); got != 2 {
+		t.Errorf("Get() has %d ignore rules, want the rules of the flags and the config", got)
+	}
+	if got := config.Defaults.Labels["team"]; got != "payments" {
+		t.Errorf("Get() default label team = %q, want payments", got)
+	}
+	if got, mirror := config.Mirrors.Rewrite("docker.io/library/nginx:1.25.0"); got != "mirror.acme.internal/hub/library/nginx:1.25.0" || mirror.PullSecret != "hub-pull" {
+		t.Errorf("Get() mirrors rewrite to %q, want the mirror of the config before the mirror of the flags", got)
+	}
+
+	// Reloading replaces the loaded config, rather than adding to it
+	store.Set(&Config{})
+	if got := store.Get(); len(got.IgnoreRules()) != 1 || len(got.Defaults.Labels) != 0 || len(got.Mirrors) != 1 {
+		t.Errorf("Get() after a reload = %+v, want the flags only", got)
+	}
+	if got := fromFlags.ImagePolicy.AllowedRegistries; len(got) != 1 {
+		t.Errorf("Set() changed the config of the flags to %v", got)
+	}
+
+	var unset *Store
+	if got := unset.ImagePolicy().Validate("docker.io/library/nginx:latest"); got != nil {
+		t.Errorf("ImagePolicy() of a nil store = %v, want no policy", got)
+	}
+}
@@ -0,0 +1,166 @@
+package operatorconfig
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// settleDelay is how long the file must go without changes before it is reloaded
+const settleDelay = 100 * time.Millisecond
+
+// FileWatcher reloads the operator config into the store whenever its file changes.  The directory of the file is
+// watched rather than the file itself, as a mounted ConfigMap is updated by swapping a symlink in that directory.  A
+// config that fails to load is logged and the config in force is kept.
+type FileWatcher struct {
+	Path  string
+	Store *Store
+
+	// loaded is the content of the file the config in force was loaded from
+	loaded []byte
+}
+
+var _ manager.LeaderElectionRunnable = &FileWatcher{}
+
+// NeedLeaderElection is false as every replica serves webhooks with the config, not only the leader
+func (w *FileWatcher) NeedLeaderElection() bool {
+	return false
+}
+
+// Start watches the file until the context is done
+func (w *FileWatcher) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("operator-config").WithValues("path", w.Path)
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	if err := watcher.Add(filepath.Dir(w.Path)); err != nil {
+		return fmt.Errorf("unable to watch the operator config %s: %w", w.Path, err)
+	}
+
+	// The file may have changed between the first load and the start of the watch
+	w.reload(logger)
+
+	// A file that is written in place raises several events, the first of them
+	// for an empty file, so the file is only reloaded once the events settle.
+	settled := time.NewTimer(0)
+	<-settled.C
+	defer settled.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case _, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			settled.Reset(settleDelay)
+		case <-settled.C:
+			w.reload(logger)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			logger.Error(err, "error watching the operator config")
+		}
+	}
+}
+
+// reload loads the file into the store when its content changed since it was last loaded
+func (w *FileWatcher) reload(logger logr.Logger) {
+	data, err := os.ReadFile(w.Path)
+	if err != nil {
+		logger.Error(err, "unable to read the operator config, keeping the config in force")
+		return
+	}
+	if w.loaded != nil && bytes.Equal(data, w.loaded) {
+		return
+	}
+
+	config, err := Parse(data)
+	if err != nil {
+		logger.Error(err, "unable to load the operator config, keeping the config in force")
+		return
+	}
+
+	w.Store.Set(config)
+	w.loaded = data
+	logger.Info("loaded the operator config")
+}
+
+// configMapWatcher runs the cache that holds the ConfigMap of the operator config.  It has a cache, so that the
+// manager starts it with its own caches on every replica, before the controllers and webhooks.
+type configMapWatcher struct {
+	cache.Cache
+}
+
+func (w *configMapWatcher) GetCache() cache.Cache {
+	return w.Cache
+}
+
+// WatchConfigMap reloads the operator config into the store whenever the ConfigMap changes.  The ConfigMap is watched
+// through a cache of its own, that holds no other object, so the watched namespaces of the manager do not apply.  A
+// config that fails to load is logged and the config in force is kept, as it is when the ConfigMap is deleted.
+func WatchConfigMap(mgr manager.Manager, namespace, name string, store *Store) error {
+	c, err := cache.New(mgr.GetConfig(), cache.Options{
+		Scheme:     mgr.GetScheme(),
+		Mapper:     mgr.GetRESTMapper(),
+		Namespaces: []string{namespace},
+		ByObject: map[client.Object]cache.ByObject{
+			&corev1.ConfigMap{}: {Field: fields.OneTermEqualSelector("metadata.name", name)},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	informer, err := c.GetInformer(context.Background(), &corev1.ConfigMap{})
+	if err != nil {
+		return err
+	}
+
+	logger := mgr.GetLogger().WithName("operator-config").WithValues("configmap", namespace+"/"+name)
+	load := func(obj interface{}) {
+		cm, ok := obj.(*corev1.ConfigMap)
+		if !ok {
+			return
+		}
+		config, err := FromConfigMap(cm)
+		if err != nil {
+			logger.Error(err, "unable to load the operator config, keeping the config in force")
+			return
+		}
+		store.Set(config)
+		logger.Info("loaded the operator config", "resourceVersion", cm.GetResourceVersion())
+	}
+	if _, err := informer.AddEventHandler(toolscache.ResourceEventHandlerFuncs{
+		AddFunc: load,
+		UpdateFunc: func(oldObj, obj interface{}) {
+			// A resync of the cache delivers the ConfigMap again unchanged
+			if oldObj.(client.Object).GetResourceVersion() != obj.(client.Object).GetResourceVersion() {
+				load(obj)
+			}
+		},
+	}); err != nil {
+		return err
+	}
+
+	return mgr.Add(&configMapWatcher{Cache: c})
+}
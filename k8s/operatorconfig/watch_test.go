@@ -0,0 +1,54 @@
+package operatorconfig
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileWatcher(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("defaults:\n  labels:\n    team: payments\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewStore(nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error)
+	go func() {
+		done <- (&FileWatcher{Path: path, Store: store}).Start(ctx)
+	}()
+
+	// waitFor polls the store, as the file is reloaded in the background
+	waitFor := func(team string) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for store.Get().Defaults.Labels["team"] != team {
+			if time.Now().After(deadline) {
+				t.Fatalf("FileWatcher default label team = %q, want %q", store.Get().Defaults.Labels["team"], team)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	waitFor("payments")
+
+	if err := os.WriteFile(path, []byte("defaults:\n  labels:\n    team: checkout\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	waitFor("checkout")
+
+	// An invalid config keeps the config in force
+	if err := os.WriteFile(path, []byte("defaults: [\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	waitFor("checkout")
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Start() error = %v", err)
+	}
+}
@@ -0,0 +1,170 @@
+package overrides
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	acmepolicy "github.com/nathanbrophy/portfolio-demo/k8s/policy"
+)
+
+// The types of patches a generated object can be overridden with
+const (
+	// StrategicMerge is a strategic merge patch, as used by kubectl patch, where lists such as containers are merged by
+	// their name
+	StrategicMerge string = "StrategicMerge"
+
+	// JSON6902 is a list of JSON patch operations, as defined by RFC 6902
+	JSON6902 string = "JSON6902"
+)
+
+// Patch is a parsed patch that can be applied to generated objects
+type Patch struct {
+	patchType string
+	data      []byte
+	ops       jsonpatch.Patch
+}
+
+// Parse parses a patch of the given type, written as YAML or JSON, so that a malformed patch is rejected before it is
+// applied to anything.  An empty type is a strategic merge patch.
+func Parse(patchType, patch string) (*Patch, error) {
+	data, err := yaml.YAMLToJSON([]byte(patch))
+	if err != nil {
+		return nil, fmt.Errorf("the patch is neither valid YAML nor JSON: %w", err)
+	}
+
+	switch patchType {
+	case "", StrategicMerge:
+		fields := map[string]interface{}{}
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return nil, fmt.Errorf("a strategic merge patch must be an object: %w", err)
+		}
+		return &Patch{patchType: StrategicMerge, data: data}, nil
+	case JSON6902:
+		ops, err := jsonpatch.DecodePatch(data)
+		if err != nil {
+			return nil, fmt.Errorf("a JSON6902 patch must be a list of operations: %w", err)
+		}
+		for i, op := range ops {
+			if err := validateOperation(op); err != nil {
+				return nil, fmt.Errorf("operation %d: %w", i, err)
+			}
+		}
+		return &Patch{patchType: JSON6902, data: data, ops: ops}, nil
+	default:
+		return nil, fmt.Errorf("unknown patch type %q, expected %s or %s", patchType, StrategicMerge, JSON6902)
+	}
+}
+
+// validateOperation checks that an operation is one of the RFC 6902 operations, with the fields that operation needs
+func validateOperation(op jsonpatch.Operation) error {
+	path, err := op.Path()
+	if err != nil {
+		return err
+	}
+	if path == "" || path[0] != '/' {
+		return fmt.Errorf("path %q is not a JSON pointer", path)
+	}
+
+	switch op.Kind() {
+	case "add", "replace", "test":
+		if _, ok := op["value"]; !ok {
+			return fmt.Errorf("%s operation on %s has no value", op.Kind(), path)
+		}
+	case "remove":
+	case "move", "copy":
+		if _, err := op.From(); err != nil {
+			return fmt.Errorf("%s operation on %s: %w", op.Kind(), path, err)
+		}
+	default:
+		return fmt.Errorf("unknown operation %q", op.Kind())
+	}
+
+	return nil
+}
+
+// Apply applies the patch to a generated object, and returns the patched object.  The patch may change any field apart
+// from the identity of the object, as the object would no longer be the one generated for the Application.
+func (p *Patch) Apply(obj client.Object) (client.Object, error) {
+	original, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	var patched []byte
+	switch p.patchType {
+	case JSON6902:
+		patched, err = p.ops.Apply(original)
+	default:
+		patched, err = strategicpatch.StrategicMergePatch(original, p.data, obj)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to apply the %s patch: %w", p.patchType, err)
+	}
+
+	out := reflect.New(reflect.TypeOf(obj).Elem()).Interface().(client.Object)
+	if err := json.Unmarshal(patched, out); err != nil {
+		return nil, fmt.Errorf("the patched object is not a valid %s: %w", obj.GetObjectKind().GroupVersionKind().Kind, err)
+	}
+
+	if out.GetObjectKind().GroupVersionKind() != obj.GetObjectKind().GroupVersionKind() ||
+		out.GetName() != obj.GetName() ||
+		out.GetNamespace() != obj.GetNamespace() {
+		return nil, fmt.Errorf("the patch changes the apiVersion, kind, name or namespace of the object")
+	}
+
+	return out, nil
+}
+
+// Images returns the images of the containers the patch adds or changes, as far as they are written in the patch.  The
+// image of a container the patch copies or moves from elsewhere in the object is not known until the patch is applied.
+func (p *Patch) Images() []string {
+	if p.patchType != JSON6902 {
+		fields := map[string]interface{}{}
+		if err := json.Unmarshal(p.data, &fields); err != nil {
+			return nil
+		}
+		return acmepolicy.ContainerImages(fields)
+	}
+
+	images := []string{}
+	for _, op := range p.ops {
+		raw, ok := op["value"]
+		if (op.Kind() != "add" && op.Kind() != "replace") || !ok || raw == nil {
+			continue
+		}
+		var value interface{}
+		if err := json.Unmarshal(*raw, &value); err != nil {
+			continue
+		}
+
+		// The value is put back in its place within an otherwise empty object, so
+		// that an image, a container or a list of containers is found as such.
+		path, _ := op.Path()
+		segments := strings.Split(path, "/")
+		for i := len(segments) - 1; i > 0; i-- {
+			if _, err := strconv.Atoi(segments[i]); err == nil || segments[i] == "-" {
+				value = []interface{}{value}
+			} else {
+				value = map[string]interface{}{unescape(segments[i]): value}
+			}
+		}
+		if fields, ok := value.(map[string]interface{}); ok {
+			images = append(images, acmepolicy.ContainerImages(fields)...)
+		}
+	}
+
+	return images
+}
+
+// unescape decodes a segment of a JSON pointer
+func unescape(segment string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(segment, "~1", "/"), "~0", "~")
+}
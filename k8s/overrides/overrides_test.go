@@ -0,0 +1,239 @@
+package overrides
+
+import (
+	"reflect"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func deployment() *appsv1.Deployment {
+	return &appsv1.Deployment{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+		Spec: appsv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "app", Image: "quay.io/acme/app:v1.0.0"}},
+				},
+			},
+		},
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name      string
+		patchType string
+		patch     string
+		wantErr   bool
+	}{
+		{
+			name:  "strategic merge as YAML",
+			patch: "spec:\n  minReadySeconds: 10\n",
+		},
+		{
+			name:      "strategic merge as JSON",
+			patchType: StrategicMerge,
+			patch:     `{"spec": {"minReadySeconds": 10}}`,
+		},
+		{
+			name:    "strategic merge that is not an object",
+			patch:   "- minReadySeconds",
+			wantErr: true,
+		},
+		{
+			name:    "malformed YAML",
+			patch:   "spec: {",
+			wantErr: true,
+		},
+		{
+			name:      "JSON6902 operations",
+			patchType: JSON6902,
+			patch:     `[{"op": "add", "path": "/spec/minReadySeconds", "value": 10}, {"op": "remove", "path": "/spec/paused"}]`,
+		},
+		{
+			name:      "JSON6902 that is not a list",
+			patchType: JSON6902,
+			patch:     `{"op": "add", "path": "/spec/minReadySeconds", "value": 10}`,
+			wantErr:   true,
+		},
+		{
+			name:      "JSON6902 unknown operation",
+			patchType: JSON6902,
+			patch:     `[{"op": "merge", "path": "/spec"}]`,
+			wantErr:   true,
+		},
+		{
+			name:      "JSON6902 add without a value",
+			patchType: JSON6902,
+			patch:     `[{"op": "add", "path": "/spec/minReadySeconds"}]`,
+			wantErr:   true,
+		},
+		{
+			name:      "JSON6902 move without a from",
+			patchType: JSON6902,
+			patch:     `[{"op": "move", "path": "/spec/minReadySeconds"}]`,
+			wantErr:   true,
+		},
+		{
+			name:      "JSON6902 path that is not a pointer",
+			patchType: JSON6902,
+			patch:     `[{"op": "remove", "path": "spec.paused"}]`,
+			wantErr:   true,
+		},
+		{
+			name:      "unknown type",
+			patchType: "MergePatch",
+			patch:     `{"spec": {}}`,
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(tt.patchType, tt.patch); (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPatch_Apply(t *testing.T) {
+	tests := []struct {
+		name      string
+		patchType string
+		patch     string
+		check     func(*testing.T, *appsv1.Deployment)
+		wantErr   bool
+	}{
+		{
+			name:  "strategic merge keeps the containers it does not name",
+			patch: "spec:\n  template:\n    spec:\n      containers:\n      - name: sidecar\n        image: quay.io/acme/sidecar:v1\n",
+			check: func(t *testing.T, got *appsv1.Deployment) {
+				if len(got.Spec.Template.Spec.Containers) != 2 {
+					t.Errorf("containers = %v, want the app and the sidecar", got.Spec.Template.Spec.Containers)
+				}
+			},
+		},
+		{
+			name:  "strategic merge merges a container by name",
+			patch: "spec:\n  template:\n    spec:\n      containers:\n      - name: app\n        imagePullPolicy: Always\n",
+			check: func(t *testing.T, got *appsv1.Deployment) {
+				container := got.Spec.Template.Spec.Containers[0]
+				if len(got.Spec.Template.Spec.Containers) != 1 || container.Image != "quay.io/acme/app:v1.0.0" || container.ImagePullPolicy != corev1.PullAlways {
+					t.Errorf("containers = %v, want the app container with the Always pull policy", got.Spec.Template.Spec.Containers)
+				}
+			},
+		},
+		{
+			name:      "JSON6902",
+			patchType: JSON6902,
+			patch:     `[{"op": "add", "path": "/spec/template/spec/priorityClassName", "value": "critical"}]`,
+			check: func(t *testing.T, got *appsv1.Deployment) {
+				if got.Spec.Template.Spec.PriorityClassName != "critical" {
+					t.Errorf("priorityClassName = %q, want critical", got.Spec.Template.Spec.PriorityClassName)
+				}
+			},
+		},
+		{
+			name:      "JSON6902 operation that fails",
+			patchType: JSON6902,
+			patch:     `[{"op": "remove", "path": "/spec/template/spec/hostname"}]`,
+			wantErr:   true,
+		},
+		{
+			name:    "changed name",
+			patch:   "metadata:\n  name: other\n",
+			wantErr: true,
+		},
+		{
+			name:      "changed kind",
+			patchType: JSON6902,
+			patch:     `[{"op": "replace", "path": "/kind", "value": "StatefulSet"}]`,
+			wantErr:   true,
+		},
+		{
+			name:    "invalid field type",
+			patch:   "spec:\n  replicas: many\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patch, err := Parse(tt.patchType, tt.patch)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+
+			original := deployment()
+			got, err := patch.Apply(original)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Patch.Apply() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if original.Spec.Template.Spec.PriorityClassName != "" || len(original.Spec.Template.Spec.Containers) != 1 {
+				t.Errorf("Patch.Apply() changed the original object")
+			}
+			tt.check(t, got.(*appsv1.Deployment))
+		})
+	}
+}
+
+func TestPatch_Images(t *testing.T) {
+	tests := []struct {
+		name      string
+		patchType string
+		patch     string
+		want      []string
+	}{
+		{
+			name:  "strategic merge container",
+			patch: "spec:\n  template:\n    spec:\n      initContainers:\n      - name: setup\n        image: quay.io/acme/setup:v1\n",
+			want:  []string{"quay.io/acme/setup:v1"},
+		},
+		{
+			name:  "strategic merge without containers",
+			patch: "spec:\n  template:\n    spec:\n      priorityClassName: critical\n",
+			want:  []string{},
+		},
+		{
+			name:      "JSON6902 image",
+			patchType: JSON6902,
+			patch:     `[{"op": "replace", "path": "/spec/template/spec/containers/0/image", "value": "quay.io/acme/app:v2"}]`,
+			want:      []string{"quay.io/acme/app:v2"},
+		},
+		{
+			name:      "JSON6902 appended container",
+			patchType: JSON6902,
+			patch:     `[{"op": "add", "path": "/spec/template/spec/containers/-", "value": {"name": "sidecar", "image": "quay.io/acme/sidecar:v1"}}]`,
+			want:      []string{"quay.io/acme/sidecar:v1"},
+		},
+		{
+			name:      "JSON6902 list of containers",
+			patchType: JSON6902,
+			patch:     `[{"op": "add", "path": "/spec/template/spec/initContainers", "value": [{"name": "setup", "image": "quay.io/acme/setup:v1"}]}, {"op": "remove", "path": "/spec/template/spec/containers/0/image"}]`,
+			want:      []string{"quay.io/acme/setup:v1"},
+		},
+		{
+			name:      "JSON6902 field that is not an image",
+			patchType: JSON6902,
+			patch:     `[{"op": "add", "path": "/metadata/annotations/image", "value": "quay.io/acme/app:v2"}]`,
+			want:      []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patch, err := Parse(tt.patchType, tt.patch)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if got := patch.Images(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Patch.Images() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package policy
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// ExtraResourcePolicy defines the operator level allowlist of the kinds an Application may embed as extra resources
+type ExtraResourcePolicy struct {
+	// AllowedKinds lists the kinds that may be embedded, as Kind for the core group or as Kind.group such as
+	// CronJob.batch, no kind is allowed when empty
+	AllowedKinds []string `json:"allowedKinds,omitempty"`
+}
+
+// ExtraResourceSource provides the extra resource policy in force, which may change while the manager runs
+type ExtraResourceSource interface {
+	ExtraResourcePolicy() *ExtraResourcePolicy
+}
+
+// ExtraResourcePolicy returns the policy itself, so that a fixed policy is an ExtraResourceSource
+func (p *ExtraResourcePolicy) ExtraResourcePolicy() *ExtraResourcePolicy {
+	return p
+}
+
+// Validate checks the kind against the allowlist, a nil policy allows no kind
+func (p *ExtraResourcePolicy) Validate(kind schema.GroupKind) error {
+	if p != nil {
+		for _, allowed := range p.AllowedKinds {
+			if schema.ParseGroupKind(allowed) == kind {
+				return nil
+			}
+		}
+	}
+
+	return fmt.Errorf("kind %s is not one of the extra resource kinds allowed by the operator", kind)
+}
@@ -0,0 +1,60 @@
+package policy
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestExtraResourcePolicy_Validate(t *testing.T) {
+	allowlist := &ExtraResourcePolicy{AllowedKinds: []string{"ConfigMap", "CronJob.batch"}}
+
+	tests := []struct {
+		name    string
+		policy  *ExtraResourcePolicy
+		kind    schema.GroupKind
+		wantErr bool
+	}{
+		{
+			name:    "no policy",
+			policy:  nil,
+			kind:    schema.GroupKind{Kind: "ConfigMap"},
+			wantErr: true,
+		},
+		{
+			name:    "empty policy",
+			policy:  &ExtraResourcePolicy{},
+			kind:    schema.GroupKind{Kind: "ConfigMap"},
+			wantErr: true,
+		},
+		{
+			name:   "core kind",
+			policy: allowlist,
+			kind:   schema.GroupKind{Kind: "ConfigMap"},
+		},
+		{
+			name:   "kind of a group",
+			policy: allowlist,
+			kind:   schema.GroupKind{Group: "batch", Kind: "CronJob"},
+		},
+		{
+			name:    "kind of another group",
+			policy:  allowlist,
+			kind:    schema.GroupKind{Group: "example.com", Kind: "ConfigMap"},
+			wantErr: true,
+		},
+		{
+			name:    "kind not allowed",
+			policy:  allowlist,
+			kind:    schema.GroupKind{Group: "rbac.authorization.k8s.io", Kind: "Role"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.policy.Validate(tt.kind); (err != nil) != tt.wantErr {
+				t.Errorf("ExtraResourcePolicy.Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
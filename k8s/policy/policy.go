@@ -0,0 +1,135 @@
+package policy
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	acmeregistry "github.com/nathanbrophy/portfolio-demo/k8s/registry"
+)
+
+// semverTag matches tags that are a full semantic version, with or without a leading v
+var semverTag = regexp.MustCompile(`^v?(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)(-[0-9A-Za-z.-]+)?(\+[0-9A-Za-z.-]+)?$`)
+
+// ImagePolicy defines the operator level rules every Application image must satisfy
+type ImagePolicy struct {
+	// AllowedRegistries lists the registry and repository prefixes images may be pulled from, any image is allowed when empty
+	AllowedRegistries []string `json:"allowedRegistries,omitempty"`
+
+	// RequireDigestOrSemver requires images to be pinned to a digest or tagged with a full semantic version
+	RequireDigestOrSemver bool `json:"requireDigestOrSemver,omitempty"`
+
+	// DeniedTags lists the image tags that may never be deployed, such as latest
+	DeniedTags []string `json:"deniedTags,omitempty"`
+}
+
+// Source provides the image policy in force, which may change while the manager runs
+type Source interface {
+	ImagePolicy() *ImagePolicy
+}
+
+// ImagePolicy returns the policy itself, so that a fixed policy is a Source
+func (p *ImagePolicy) ImagePolicy() *ImagePolicy {
+	return p
+}
+
+// Violation is the error returned for an image that does not satisfy the policy
+type Violation struct {
+	Image   string
+	Reasons []string
+}
+
+func (v *Violation) Error() string {
+	return fmt.Sprintf("image %s violates the image policy: %s", v.Image, strings.Join(v.Reasons, "; "))
+}
+
+// Validate checks the image against the policy, returning a *Violation describing every rule the image breaks
+func (p *ImagePolicy) Validate(image string) error {
+	if p == nil {
+		return nil
+	}
+
+	ref, err := acmeregistry.ParseReference(image)
+	if err != nil {
+		return &Violation{Image: image, Reasons: []string{err.Error()}}
+	}
+
+	reasons := []string{}
+
+	if len(p.AllowedRegistries) > 0 && !p.allowed(ref) {
+		reasons = append(reasons, fmt.Sprintf("registry is not one of the allowed registries %v", p.AllowedRegistries))
+	}
+
+	if p.RequireDigestOrSemver && ref.Digest == "" && !semverTag.MatchString(ref.Tag) {
+		reasons = append(reasons, fmt.Sprintf("tag %q is not a semantic version and the image is not pinned to a digest", ref.Tag))
+	}
+
+	for _, denied := range p.DeniedTags {
+		if ref.Tag == denied {
+			reasons = append(reasons, fmt.Sprintf("tag %q is denied", ref.Tag))
+		}
+	}
+
+	if len(reasons) > 0 {
+		return &Violation{Image: image, Reasons: reasons}
+	}
+
+	return nil
+}
+
+// allowed checks the fully qualified image name against the allowed prefixes, a prefix only matches on
+// a path boundary so that quay.io/acme does not allow quay.io/acme-other
+func (p *ImagePolicy) allowed(ref acmeregistry.Reference) bool {
+	name := ref.Registry + "/" + ref.Repository
+
+	for _, prefix := range p.AllowedRegistries {
+		prefix = strings.TrimSuffix(prefix, "/")
+		if name == prefix || strings.HasPrefix(name, prefix+"/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ContainerImages lists the image of every container, init container and ephemeral container in any pod spec held by
+// an object in its unstructured form.  The whole object is searched, rather than a known pod template, so that the
+// containers of any kind of workload are found, such as the job template of a CronJob, or a container added to a
+// Deployment by an override.
+func ContainerImages(content map[string]interface{}) []string {
+	images := []string{}
+	collectImages(content, &images)
+
+	return images
+}
+
+// collectImages adds the images of the containers found anywhere below the unstructured value, in a stable order
+func collectImages(value interface{}, images *[]string) {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(typed))
+		for key := range typed {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			if key == "containers" || key == "initContainers" || key == "ephemeralContainers" {
+				containers, _ := typed[key].([]interface{})
+				for _, container := range containers {
+					if fields, ok := container.(map[string]interface{}); ok {
+						if image, ok := fields["image"].(string); ok {
+							*images = append(*images, image)
+						}
+					}
+				}
+			}
+			collectImages(typed[key], images)
+		}
+	case []interface{}:
+		for _, item := range typed {
+			collectImages(item, images)
+		}
+	}
+}
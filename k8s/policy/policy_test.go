@@ -0,0 +1,119 @@
+package policy
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestImagePolicy_Validate(t *testing.T) {
+	strict := &ImagePolicy{
+		AllowedRegistries:     []string{"quay.io/acme", "docker.io/library/"},
+		RequireDigestOrSemver: true,
+		DeniedTags:            []string{"latest"},
+	}
+
+	tests := []struct {
+		name    string
+		policy  *ImagePolicy
+		image   string
+		reasons int
+	}{
+		{
+			name:   "no policy",
+			policy: nil,
+			image:  "anything:latest",
+		},
+		{
+			name:   "empty policy",
+			policy: &ImagePolicy{},
+			image:  "example.com/anything:dev",
+		},
+		{
+			name:   "semver tag from allowed registry",
+			policy: strict,
+			image:  "quay.io/acme/app:v1.2.3",
+		},
+		{
+			name:   "digest from allowed registry",
+			policy: strict,
+			image:  "quay.io/acme/app@sha256:abc123",
+		},
+		{
+			name:   "implicit docker hub registry",
+			policy: strict,
+			image:  "nginx:1.25.0",
+		},
+		{
+			name:    "registry prefix only matches on a path boundary",
+			policy:  strict,
+			image:   "quay.io/acme-other/app:v1.2.3",
+			reasons: 1,
+		},
+		{
+			name:    "non semver tag",
+			policy:  strict,
+			image:   "quay.io/acme/app:dev",
+			reasons: 1,
+		},
+		{
+			name:    "implicit latest tag",
+			policy:  strict,
+			image:   "quay.io/acme/app",
+			reasons: 2,
+		},
+		{
+			name:    "every rule broken",
+			policy:  strict,
+			image:   "example.com/app:latest",
+			reasons: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Validate(tt.image)
+			if tt.reasons == 0 {
+				if err != nil {
+					t.Errorf("ImagePolicy.Validate() error = %v, want nil", err)
+				}
+				return
+			}
+
+			violation := &Violation{}
+			if !errors.As(err, &violation) {
+				t.Fatalf("ImagePolicy.Validate() error = %v, want a *Violation", err)
+			}
+			if len(violation.Reasons) != tt.reasons {
+				t.Errorf("ImagePolicy.Validate() reasons = %v, want %d reasons", violation.Reasons, tt.reasons)
+			}
+		})
+	}
+}
+
+func TestContainerImages(t *testing.T) {
+	cronJob := map[string]interface{}{
+		"apiVersion": "batch/v1",
+		"kind":       "CronJob",
+		"spec": map[string]interface{}{
+			"jobTemplate": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"template": map[string]interface{}{
+						"spec": map[string]interface{}{
+							"initContainers":      []interface{}{map[string]interface{}{"name": "setup", "image": "quay.io/acme/setup:v1.0.0"}},
+							"containers":          []interface{}{map[string]interface{}{"name": "job", "image": "quay.io/acme/job:v1.0.0"}},
+							"ephemeralContainers": []interface{}{map[string]interface{}{"name": "debug", "image": "busybox"}},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	want := []string{"quay.io/acme/job:v1.0.0", "busybox", "quay.io/acme/setup:v1.0.0"}
+	if got := ContainerImages(cronJob); !reflect.DeepEqual(got, want) {
+		t.Errorf("ContainerImages() = %v, want %v", got, want)
+	}
+	if got := ContainerImages(map[string]interface{}{"data": map[string]interface{}{"image": "not a container"}}); len(got) != 0 {
+		t.Errorf("ContainerImages() of an object without containers = %v, want none", got)
+	}
+}
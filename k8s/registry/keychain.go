@@ -0,0 +1,104 @@
+package registry
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Credential holds the login for a single registry, as stored in a docker config file
+type Credential struct {
+	Username      string `json:"username,omitempty"`
+	Password      string `json:"password,omitempty"`
+	Auth          string `json:"auth,omitempty"`
+	IdentityToken string `json:"identitytoken,omitempty"`
+}
+
+// Keychain maps a registry host to the credential used to log in to it
+type Keychain map[string]Credential
+
+// dockerConfigJSON is the format of the .dockerconfigjson key of a kubernetes.io/dockerconfigjson secret
+type dockerConfigJSON struct {
+	Auths Keychain `json:"auths"`
+}
+
+// KeychainFromSecret reads the registry credentials out of an image pull secret, both
+// the kubernetes.io/dockerconfigjson and the legacy kubernetes.io/dockercfg types are supported
+func KeychainFromSecret(secret *corev1.Secret) (Keychain, error) {
+	keychain := Keychain{}
+
+	switch secret.Type {
+	case corev1.SecretTypeDockerConfigJson:
+		config := dockerConfigJSON{}
+		if err := json.Unmarshal(secret.Data[corev1.DockerConfigJsonKey], &config); err != nil {
+			return nil, fmt.Errorf("cannot decode pull secret %s: %w", secret.Name, err)
+		}
+		keychain = config.Auths
+	case corev1.SecretTypeDockercfg:
+		if err := json.Unmarshal(secret.Data[corev1.DockerConfigKey], &keychain); err != nil {
+			return nil, fmt.Errorf("cannot decode pull secret %s: %w", secret.Name, err)
+		}
+	default:
+		return nil, fmt.Errorf("pull secret %s has unsupported type %s", secret.Name, secret.Type)
+	}
+
+	normalized := Keychain{}
+	for host, cred := range keychain {
+		normalized[normalizeHost(host)] = cred
+	}
+
+	return normalized, nil
+}
+
+// Merge adds the credentials of other to the keychain, keeping the existing credential for hosts present in both
+func (k Keychain) Merge(other Keychain) {
+	for host, cred := range other {
+		if _, ok := k[host]; !ok {
+			k[host] = cred
+		}
+	}
+}
+
+// Lookup returns the username and password to log in to the registry with, and false when there is no credential for it
+func (k Keychain) Lookup(registry string) (string, string, bool) {
+	cred, ok := k[normalizeHost(registry)]
+	if !ok {
+		return "", "", false
+	}
+
+	if cred.IdentityToken != "" {
+		return "<token>", cred.IdentityToken, true
+	}
+
+	if cred.Username == "" && cred.Auth != "" {
+		decoded, err := base64.StdEncoding.DecodeString(cred.Auth)
+		if err != nil {
+			return "", "", false
+		}
+		user, pass, found := strings.Cut(string(decoded), ":")
+		if !found {
+			return "", "", false
+		}
+		return user, pass, true
+	}
+
+	return cred.Username, cred.Password, true
+}
+
+// normalizeHost strips the scheme and path a docker config may have on a registry key, and folds
+// the many names of Docker Hub into one
+func normalizeHost(host string) string {
+	host = strings.TrimPrefix(host, "https://")
+	host = strings.TrimPrefix(host, "http://")
+	host, _, _ = strings.Cut(host, "/")
+
+	switch host {
+	case "index.docker.io", dockerHubAPI:
+		return DockerHub
+	}
+
+	return host
+}
@@ -0,0 +1,99 @@
+package registry
+
+import (
+	"encoding/base64"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestKeychainFromSecret(t *testing.T) {
+	auth := base64.StdEncoding.EncodeToString([]byte("robot:s3cret"))
+
+	tests := []struct {
+		name     string
+		secret   *corev1.Secret
+		registry string
+		wantUser string
+		wantPass string
+		wantOK   bool
+		wantErr  bool
+	}{
+		{
+			name: "dockerconfigjson with auth",
+			secret: &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "pull"},
+				Type:       corev1.SecretTypeDockerConfigJson,
+				Data: map[string][]byte{
+					corev1.DockerConfigJsonKey: []byte(`{"auths":{"https://quay.io/v2/":{"auth":"` + auth + `"}}}`),
+				},
+			},
+			registry: "quay.io",
+			wantUser: "robot",
+			wantPass: "s3cret",
+			wantOK:   true,
+		},
+		{
+			name: "dockercfg with username and password for docker hub",
+			secret: &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "pull"},
+				Type:       corev1.SecretTypeDockercfg,
+				Data: map[string][]byte{
+					corev1.DockerConfigKey: []byte(`{"https://index.docker.io/v1/":{"username":"user","password":"pass"}}`),
+				},
+			},
+			registry: "docker.io",
+			wantUser: "user",
+			wantPass: "pass",
+			wantOK:   true,
+		},
+		{
+			name: "no login for registry",
+			secret: &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "pull"},
+				Type:       corev1.SecretTypeDockerConfigJson,
+				Data: map[string][]byte{
+					corev1.DockerConfigJsonKey: []byte(`{"auths":{"quay.io":{"auth":"` + auth + `"}}}`),
+				},
+			},
+			registry: "example.com",
+			wantOK:   false,
+		},
+		{
+			name: "unsupported secret type",
+			secret: &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "pull"},
+				Type:       corev1.SecretTypeOpaque,
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keychain, err := KeychainFromSecret(tt.secret)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("KeychainFromSecret() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			user, pass, ok := keychain.Lookup(tt.registry)
+			if user != tt.wantUser || pass != tt.wantPass || ok != tt.wantOK {
+				t.Errorf("Keychain.Lookup() = %v, %v, %v, want %v, %v, %v", user, pass, ok, tt.wantUser, tt.wantPass, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestKeychain_Merge(t *testing.T) {
+	keychain := Keychain{"quay.io": {Username: "first"}}
+	keychain.Merge(Keychain{
+		"quay.io":     {Username: "second"},
+		"example.com": {Username: "other"},
+	})
+
+	if keychain["quay.io"].Username != "first" || keychain["example.com"].Username != "other" {
+		t.Errorf("Keychain.Merge() = %v", keychain)
+	}
+}
@@ -0,0 +1,124 @@
+package registry
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// MirrorsConfigMapKey is the key of the ConfigMap holding the mirror rules as a YAML list
+const MirrorsConfigMapKey string = "mirrors.yaml"
+
+// Mirror is a rule that rewrites images from a source registry to a mirror registry
+type Mirror struct {
+	// Source is the registry, or registry and repository prefix, the images are pulled from, such as docker.io
+	Source string `json:"source"`
+
+	// Mirror is the registry, or registry and repository prefix, the images are pulled through instead
+	Mirror string `json:"mirror"`
+
+	// PullSecret is the name of the image pull secret holding the login for the mirror, in the Application's namespace
+	PullSecret string `json:"pullSecret,omitempty"`
+}
+
+// Mirrors is an ordered list of mirror rules, where the first matching rule wins
+type Mirrors []Mirror
+
+// ParseMirrors parses mirror rules written as a comma separated list of source=mirror pairs, every
+// rule is given the same pull secret
+func ParseMirrors(in string, pullSecret string) (Mirrors, error) {
+	mirrors := Mirrors{}
+
+	for _, rule := range strings.Split(in, ",") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+
+		source, mirror, found := strings.Cut(rule, "=")
+		if !found || source == "" || mirror == "" {
+			return nil, fmt.Errorf("mirror rule %q is not of the form source=mirror", rule)
+		}
+		mirrors = append(mirrors, Mirror{Source: source, Mirror: mirror, PullSecret: pullSecret})
+	}
+
+	return mirrors, nil
+}
+
+// MirrorsFromConfigMap reads the mirror rules from the mirrors.yaml key of a ConfigMap
+func MirrorsFromConfigMap(cm *corev1.ConfigMap) (Mirrors, error) {
+	mirrors := Mirrors{}
+	if err := yaml.UnmarshalStrict([]byte(cm.Data[MirrorsConfigMapKey]), &mirrors); err != nil {
+		return nil, fmt.Errorf("cannot decode mirror rules from ConfigMap %s/%s: %w", cm.Namespace, cm.Name, err)
+	}
+
+	for _, m := range mirrors {
+		if m.Source == "" || m.Mirror == "" {
+			return nil, fmt.Errorf("mirror rule %+v in ConfigMap %s/%s needs both a source and a mirror", m, cm.Namespace, cm.Name)
+		}
+	}
+
+	return mirrors, nil
+}
+
+// Rewrite returns the image pulled through the first mirror whose source matches it, along with
+// that mirror.  An image that no rule matches is returned as is with a nil mirror.
+func (m Mirrors) Rewrite(image string) (string, *Mirror) {
+	ref, err := ParseReference(image)
+	if err != nil {
+		return image, nil
+	}
+
+	for i := range m {
+		if rest, ok := cutPrefix(ref.Registry+"/"+ref.Repository, m[i].Source); ok {
+			return render(strings.TrimSuffix(m[i].Mirror, "/")+rest, ref), &m[i]
+		}
+	}
+
+	return image, nil
+}
+
+// Restore maps an image that was rewritten to a mirror back to the source image it was rewritten from, so
+// that the image can be judged on where it really comes from.  Other images are returned as is.
+func (m Mirrors) Restore(image string) string {
+	ref, err := ParseReference(image)
+	if err != nil {
+		return image
+	}
+
+	for i := range m {
+		if rest, ok := cutPrefix(ref.Registry+"/"+ref.Repository, m[i].Mirror); ok {
+			return render(strings.TrimSuffix(m[i].Source, "/")+rest, ref)
+		}
+	}
+
+	return image
+}
+
+// cutPrefix removes a registry or repository prefix from a fully qualified image name, where the
+// prefix only matches on a path boundary
+func cutPrefix(name, prefix string) (string, bool) {
+	prefix = strings.TrimSuffix(prefix, "/")
+	if name == prefix {
+		return "", true
+	}
+	if strings.HasPrefix(name, prefix+"/") {
+		return name[len(prefix):], true
+	}
+
+	return "", false
+}
+
+// render writes the image name with the tag and digest of the reference
+func render(name string, ref Reference) string {
+	if ref.Tag != "" {
+		name = name + ":" + ref.Tag
+	}
+	if ref.Digest != "" {
+		name = name + "@" + ref.Digest
+	}
+
+	return name
+}
@@ -0,0 +1,141 @@
+package registry
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestParseMirrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    Mirrors
+		wantErr bool
+	}{
+		{
+			name: "empty",
+			in:   "",
+			want: Mirrors{},
+		},
+		{
+			name: "rules",
+			in:   "docker.io=mirror.acme.internal/docker-hub, quay.io=mirror.acme.internal/quay",
+			want: Mirrors{
+				{Source: "docker.io", Mirror: "mirror.acme.internal/docker-hub", PullSecret: "mirror-pull"},
+				{Source: "quay.io", Mirror: "mirror.acme.internal/quay", PullSecret: "mirror-pull"},
+			},
+		},
+		{
+			name:    "malformed rule",
+			in:      "docker.io",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseMirrors(tt.in, "mirror-pull")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseMirrors() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseMirrors() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMirrorsFromConfigMap(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    Mirrors
+		wantErr bool
+	}{
+		{
+			name: "rules",
+			data: "- source: docker.io\n  mirror: mirror.acme.internal/docker-hub\n  pullSecret: mirror-pull\n",
+			want: Mirrors{
+				{Source: "docker.io", Mirror: "mirror.acme.internal/docker-hub", PullSecret: "mirror-pull"},
+			},
+		},
+		{
+			name:    "missing mirror",
+			data:    "- source: docker.io\n",
+			wantErr: true,
+		},
+		{
+			name:    "unknown field",
+			data:    "- source: docker.io\n  mirror: mirror.acme.internal\n  secret: typo\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cm := &corev1.ConfigMap{Data: map[string]string{MirrorsConfigMapKey: tt.data}}
+			got, err := MirrorsFromConfigMap(cm)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("MirrorsFromConfigMap() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MirrorsFromConfigMap() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMirrors_RewriteAndRestore(t *testing.T) {
+	mirrors := Mirrors{
+		{Source: "docker.io", Mirror: "mirror.acme.internal/docker-hub", PullSecret: "hub-pull"},
+		{Source: "quay.io/acme", Mirror: "mirror.acme.internal/acme"},
+	}
+
+	tests := []struct {
+		name       string
+		image      string
+		want       string
+		wantMirror string
+		restored   string
+	}{
+		{
+			name:       "implicit docker hub image",
+			image:      "nginx:1.25.0",
+			want:       "mirror.acme.internal/docker-hub/library/nginx:1.25.0",
+			wantMirror: "hub-pull",
+			restored:   "docker.io/library/nginx:1.25.0",
+		},
+		{
+			name:     "repository prefix with digest",
+			image:    "quay.io/acme/app@sha256:abc123",
+			want:     "mirror.acme.internal/acme/app@sha256:abc123",
+			restored: "quay.io/acme/app@sha256:abc123",
+		},
+		{
+			name:     "prefix only matches on a path boundary",
+			image:    "quay.io/acme-other/app:v1",
+			want:     "quay.io/acme-other/app:v1",
+			restored: "quay.io/acme-other/app:v1",
+		},
+		{
+			name:     "unmatched registry",
+			image:    "example.com/app:v1",
+			want:     "example.com/app:v1",
+			restored: "example.com/app:v1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, mirror := mirrors.Rewrite(tt.image)
+			if got != tt.want {
+				t.Errorf("Mirrors.Rewrite() = %v, want %v", got, tt.want)
+			}
+			if mirror != nil && mirror.PullSecret != tt.wantMirror {
+				t.Errorf("Mirrors.Rewrite() pull secret = %v, want %v", mirror.PullSecret, tt.wantMirror)
+			}
+			if restored := mirrors.Restore(got); restored != tt.restored {
+				t.Errorf("Mirrors.Restore() = %v, want %v", restored, tt.restored)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package registry
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// DockerHub is the registry domain implied by image references that do not name a registry
+	DockerHub string = "docker.io"
+
+	// dockerHubAPI is the host serving the registry API for Docker Hub
+	dockerHubAPI string = "registry-1.docker.io"
+
+	defaultTag string = "latest"
+)
+
+// Reference is a parsed container image reference
+type Reference struct {
+	// Name is the image name exactly as written, without the tag or digest
+	Name string
+
+	// Registry is the registry domain of the image, with Docker Hub made explicit
+	Registry string
+
+	// Repository is the repository path of the image within the registry
+	Repository string
+
+	// Tag is the image tag, and is empty when the reference only holds a digest
+	Tag string
+
+	// Digest is the image digest, and is empty when the reference is not pinned
+	Digest string
+}
+
+// ParseReference parses an image reference in the same way the container runtime does, so
+// "nginx" is read as docker.io/library/nginx:latest
+func ParseReference(image string) (Reference, error) {
+	if image == "" {
+		return Reference{}, fmt.Errorf("image reference is empty")
+	}
+
+	ref := Reference{}
+	name := image
+
+	if i := strings.Index(name, "@"); i >= 0 {
+		name, ref.Digest = name[:i], name[i+1:]
+		if !strings.Contains(ref.Digest, ":") {
+			return Reference{}, fmt.Errorf("image reference %q has an invalid digest", image)
+		}
+	}
+
+	// A colon after the last slash separates the tag, any other colon is a registry port.
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name, ref.Tag = name[:i], name[i+1:]
+	}
+
+	if name == "" || strings.HasSuffix(name, "/") {
+		return Reference{}, fmt.Errorf("image reference %q has no repository", image)
+	}
+
+	if ref.Tag == "" && ref.Digest == "" {
+		ref.Tag = defaultTag
+	}
+
+	ref.Name = name
+	ref.Registry = DockerHub
+	ref.Repository = name
+
+	if i := strings.Index(name, "/"); i >= 0 {
+		domain := name[:i]
+		if strings.ContainsAny(domain, ".:") || domain == "localhost" {
+			ref.Registry = domain
+			ref.Repository = name[i+1:]
+		}
+	}
+
+	if ref.Registry == DockerHub && !strings.Contains(ref.Repository, "/") {
+		ref.Repository = "library/" + ref.Repository
+	}
+
+	return ref, nil
+}
+
+// Identifier is the tag or digest used to look the manifest of the image up in the registry
+func (r Reference) Identifier() string {
+	if r.Digest != "" {
+		return r.Digest
+	}
+
+	return r.Tag
+}
+
+// Pinned renders the image reference pinned to the given digest
+func (r Reference) Pinned(digest string) string {
+	return fmt.Sprintf("%s@%s", r.Name, digest)
+}
+
+// apiHost is the host serving the registry API for the image
+func (r Reference) apiHost() string {
+	if r.Registry == DockerHub {
+		return dockerHubAPI
+	}
+
+	return r.Registry
+}
@@ -0,0 +1,100 @@
+package registry
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseReference(t *testing.T) {
+	tests := []struct {
+		name    string
+		image   string
+		want    Reference
+		wantErr bool
+	}{
+		{
+			name:  "docker hub short name",
+			image: "nginx",
+			want: Reference{
+				Name:       "nginx",
+				Registry:   "docker.io",
+				Repository: "library/nginx",
+				Tag:        "latest",
+			},
+		},
+		{
+			name:  "docker hub with namespace and tag",
+			image: "nathanbrophy/example-server:v1.0.0",
+			want: Reference{
+				Name:       "nathanbrophy/example-server",
+				Registry:   "docker.io",
+				Repository: "nathanbrophy/example-server",
+				Tag:        "v1.0.0",
+			},
+		},
+		{
+			name:  "registry with port",
+			image: "localhost:5000/team/app:v2",
+			want: Reference{
+				Name:       "localhost:5000/team/app",
+				Registry:   "localhost:5000",
+				Repository: "team/app",
+				Tag:        "v2",
+			},
+		},
+		{
+			name:  "pinned reference",
+			image: "example.com/test-image@sha256:abc123",
+			want: Reference{
+				Name:       "example.com/test-image",
+				Registry:   "example.com",
+				Repository: "test-image",
+				Digest:     "sha256:abc123",
+			},
+		},
+		{
+			name:  "tag and digest",
+			image: "example.com/test-image:v1.0@sha256:abc123",
+			want: Reference{
+				Name:       "example.com/test-image",
+				Registry:   "example.com",
+				Repository: "test-image",
+				Tag:        "v1.0",
+				Digest:     "sha256:abc123",
+			},
+		},
+		{
+			name:    "empty reference",
+			image:   "",
+			wantErr: true,
+		},
+		{
+			name:    "invalid digest",
+			image:   "example.com/test-image@abc123",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseReference(tt.image)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseReference() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseReference() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReference_Pinned(t *testing.T) {
+	ref, err := ParseReference("example.com/test-image:v1.0")
+	if err != nil {
+		t.Fatalf("ParseReference() error = %v", err)
+	}
+
+	want := "example.com/test-image@sha256:abc123"
+	if got := ref.Pinned("sha256:abc123"); got != want {
+		t.Errorf("Reference.Pinned() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,259 @@
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// manifestMediaTypes are the manifest formats accepted from the registry, index types are listed
+// first so a multi-arch image resolves to the digest of its index rather than a single platform
+var manifestMediaTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// Resolver defines the methods required to resolve an image reference to the digest it currently points at
+type Resolver interface {
+	// Resolve returns the digest for the image, using the keychain to authenticate against the registry
+	Resolve(ctx context.Context, image string, keychain Keychain) (string, error)
+}
+
+// HTTPResolver implements the Resolver interface against the OCI distribution (registry v2) API
+type HTTPResolver struct {
+	// Client is the HTTP client used to talk to registries, and defaults to a client with a 30s timeout
+	Client *http.Client
+
+	// InsecureRegistries lists the registry hosts that are served over plain HTTP
+	InsecureRegistries []string
+}
+
+// Resolve implements the Resolver interface, a reference that is already pinned is returned as is
+func (h *HTTPResolver) Resolve(ctx context.Context, image string, keychain Keychain) (string, error) {
+	ref, err := ParseReference(image)
+	if err != nil {
+		return "", err
+	}
+
+	if ref.Digest != "" {
+		return ref.Digest, nil
+	}
+
+	manifestURL := fmt.Sprintf("%s://%s/v2/%s/manifests/%s", h.scheme(ref.Registry), ref.apiHost(), ref.Repository, ref.Identifier())
+
+	// A HEAD request is enough for most registries, and does not count against
+	// pull rate limits, but the digest header is optional so GET is the fallback.
+	for _, method := range []string{http.MethodHead, http.MethodGet} {
+		resp, err := h.do(ctx, method, manifestURL, ref, keychain)
+		if err != nil {
+			return "", err
+		}
+
+		digest, err := digestFrom(resp)
+		resp.Body.Close()
+		if err != nil {
+			return "", err
+		}
+		if digest != "" {
+			return digest, nil
+		}
+	}
+
+	return "", fmt.Errorf("registry %s did not return a digest for %s", ref.Registry, image)
+}
+
+func (h *HTTPResolver) client() *http.Client {
+	if h.Client == nil {
+		return &http.Client{Timeout: 30 * time.Second}
+	}
+
+	return h.Client
+}
+
+func (h *HTTPResolver) scheme(registry string) string {
+	for _, insecure := range h.InsecureRegistries {
+		if insecure == registry {
+			return "http"
+		}
+	}
+
+	return "https"
+}
+
+// do performs a manifest request, and answers an authentication challenge from the registry once
+func (h *HTTPResolver) do(ctx context.Context, method, manifestURL string, ref Reference, keychain Keychain) (*http.Response, error) {
+	resp, err := h.request(ctx, method, manifestURL, "")
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+
+		authorization, err := h.authorize(ctx, challenge, ref, keychain)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err = h.request(ctx, method, manifestURL, authorization)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("registry %s returned %s for %s:%s", ref.Registry, resp.Status, ref.Repository, ref.Identifier())
+	}
+
+	return resp, nil
+}
+
+func (h *HTTPResolver) request(ctx context.Context, method, target, authorization string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, target, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", strings.Join(manifestMediaTypes, ", "))
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+
+	return h.client().Do(req)
+}
+
+// authorize answers the WWW-Authenticate challenge of the registry, returning the Authorization header to retry with
+func (h *HTTPResolver) authorize(ctx context.Context, challenge string, ref Reference, keychain Keychain) (string, error) {
+	scheme, params := parseChallenge(challenge)
+	user, pass, hasCredential := keychain.Lookup(ref.Registry)
+
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if !hasCredential {
+			return "", fmt.Errorf("registry %s requires credentials, but none of the image pull secrets hold a login for it", ref.Registry)
+		}
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		req.SetBasicAuth(user, pass)
+		return req.Header.Get("Authorization"), nil
+	case "bearer":
+		return h.token(ctx, params, ref, user, pass, hasCredential)
+	}
+
+	return "", fmt.Errorf("registry %s sent an unsupported authentication challenge %q", ref.Registry, challenge)
+}
+
+// token exchanges the registry credential, or an anonymous login, for a bearer token scoped to pulling the repository
+func (h *HTTPResolver) token(ctx context.Context, params map[string]string, ref Reference, user, pass string, hasCredential bool) (string, error) {
+	realm, err := url.Parse(params["realm"])
+	if err != nil || params["realm"] == "" {
+		return "", fmt.Errorf("registry %s sent a bearer challenge without a valid realm", ref.Registry)
+	}
+
+	query := realm.Query()
+	if service, ok := params["service"]; ok {
+		query.Set("service", service)
+	}
+	query.Set("scope", fmt.Sprintf("repository:%s:pull", ref.Repository))
+	realm.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	if hasCredential {
+		req.SetBasicAuth(user, pass)
+	}
+
+	resp, err := h.client().Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token service for registry %s returned %s", ref.Registry, resp.Status)
+	}
+
+	body := struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("cannot decode token from registry %s: %w", ref.Registry, err)
+	}
+
+	token := body.Token
+	if token == "" {
+		token = body.AccessToken
+	}
+
+	return "Bearer " + token, nil
+}
+
+// digestFrom reads the digest of the manifest in the response, falling back to hashing the body of a GET
+// response, an empty digest is returned when a HEAD response does not carry the digest header
+func digestFrom(resp *http.Response) (string, error) {
+	if digest := resp.Header.Get("Docker-Content-Digest"); digest != "" {
+		return digest, nil
+	}
+
+	if resp.Request == nil || resp.Request.Method != http.MethodGet {
+		return "", nil
+	}
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, resp.Body); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("sha256:%x", hasher.Sum(nil)), nil
+}
+
+// parseChallenge splits a WWW-Authenticate header such as
+//
+//	Bearer realm="https://auth.example.com/token",service="registry.example.com"
+//
+// into its scheme and parameters
+func parseChallenge(challenge string) (string, map[string]string) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(challenge), " ")
+	params := map[string]string{}
+
+	for _, param := range splitParams(rest) {
+		key, value, found := strings.Cut(strings.TrimSpace(param), "=")
+		if !found {
+			continue
+		}
+		params[strings.ToLower(key)] = strings.Trim(value, `"`)
+	}
+
+	return scheme, params
+}
+
+// splitParams splits challenge parameters on the commas that are not inside a quoted value
+func splitParams(in string) []string {
+	params := []string{}
+	quoted := false
+	start := 0
+
+	for i, c := range in {
+		switch {
+		case c == '"':
+			quoted = !quoted
+		case c == ',' && !quoted:
+			params = append(params, in[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(params, in[start:])
+}
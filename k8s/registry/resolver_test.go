@@ -0,0 +1,174 @@
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const (
+	testDigest   string = "sha256:0123456789abcdef"
+	testManifest string = `{"schemaVersion":2}`
+)
+
+// newTestRegistry starts a stand-in for a registry serving the team/app repository, every auth mode
+// other than "anonymous" requires the robot:s3cret login
+func newTestRegistry(t *testing.T, auth string, digestHeader bool) *httptest.Server {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != "robot" || pass != "s3cret" || r.URL.Query().Get("scope") != "repository:team/app:pull" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"token":"good-token"}`)
+	})
+
+	mux.HandleFunc("/v2/team/app/manifests/", func(w http.ResponseWriter, r *http.Request) {
+		switch auth {
+		case "bearer":
+			if r.Header.Get("Authorization") != "Bearer good-token" {
+				w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="registry"`, server.URL))
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+		case "basic":
+			if user, pass, ok := r.BasicAuth(); !ok || user != "robot" || pass != "s3cret" {
+				w.Header().Set("WWW-Authenticate", `Basic realm="registry"`)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+		}
+
+		if !strings.HasSuffix(r.URL.Path, "/v1") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		if digestHeader {
+			w.Header().Set("Docker-Content-Digest", testDigest)
+		}
+		if r.Method == http.MethodGet {
+			fmt.Fprint(w, testManifest)
+		}
+	})
+
+	return server
+}
+
+func TestHTTPResolver_Resolve(t *testing.T) {
+	keychain := Keychain{}
+	login := func(host string) Keychain {
+		return Keychain{host: {Username: "robot", Password: "s3cret"}}
+	}
+	bodyDigest := fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(testManifest)))
+
+	tests := []struct {
+		name         string
+		auth         string
+		digestHeader bool
+		tag          string
+		withLogin    bool
+		want         string
+		wantErr      bool
+	}{
+		{
+			name:         "anonymous registry",
+			auth:         "anonymous",
+			digestHeader: true,
+			tag:          "v1",
+			want:         testDigest,
+		},
+		{
+			name:         "bearer token registry",
+			auth:         "bearer",
+			digestHeader: true,
+			tag:          "v1",
+			withLogin:    true,
+			want:         testDigest,
+		},
+		{
+			name:         "basic auth registry",
+			auth:         "basic",
+			digestHeader: true,
+			tag:          "v1",
+			withLogin:    true,
+			want:         testDigest,
+		},
+		{
+			name:         "digest computed from the manifest",
+			auth:         "anonymous",
+			digestHeader: false,
+			tag:          "v1",
+			want:         bodyDigest,
+		},
+		{
+			name:         "missing login",
+			auth:         "basic",
+			digestHeader: true,
+			tag:          "v1",
+			wantErr:      true,
+		},
+		{
+			name:         "unknown tag",
+			auth:         "anonymous",
+			digestHeader: true,
+			tag:          "v2",
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestRegistry(t, tt.auth, tt.digestHeader)
+			host := strings.TrimPrefix(server.URL, "http://")
+
+			resolver := &HTTPResolver{
+				Client:             server.Client(),
+				InsecureRegistries: []string{host},
+			}
+
+			creds := keychain
+			if tt.withLogin {
+				creds = login(host)
+			}
+
+			got, err := resolver.Resolve(context.Background(), fmt.Sprintf("%s/team/app:%s", host, tt.tag), creds)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("HTTPResolver.Resolve() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("HTTPResolver.Resolve() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTTPResolver_ResolvePinned(t *testing.T) {
+	resolver := &HTTPResolver{}
+
+	got, err := resolver.Resolve(context.Background(), "example.com/test-image@"+testDigest, Keychain{})
+	if err != nil {
+		t.Fatalf("HTTPResolver.Resolve() error = %v", err)
+	}
+	if got != testDigest {
+		t.Errorf("HTTPResolver.Resolve() = %v, want %v", got, testDigest)
+	}
+}
+
+func Test_parseChallenge(t *testing.T) {
+	scheme, params := parseChallenge(`Bearer realm="https://auth.example.com/token",service="registry.example.com",scope="repository:a:pull,push"`)
+
+	if scheme != "Bearer" {
+		t.Errorf("parseChallenge() scheme = %v, want Bearer", scheme)
+	}
+	if params["realm"] != "https://auth.example.com/token" || params["service"] != "registry.example.com" || params["scope"] != "repository:a:pull,push" {
+		t.Errorf("parseChallenge() params = %v", params)
+	}
+}
@@ -0,0 +1,133 @@
+package revisions
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"sort"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/rand"
+
+	acmeiov1beta1 "github.com/nathanbrophy/portfolio-demo/k8s/api/v1beta1"
+)
+
+const (
+	// ApplicationLabel is set on every ControllerRevision to the name of the Application that recorded it
+	ApplicationLabel string = "acme.io/application"
+
+	// HashLabel is set on every ControllerRevision to the hash of the spec it holds
+	HashLabel string = "acme.io/revision-hash"
+)
+
+// recordable strips the fields of the spec that only drive revision bookkeeping, or that pause or
+// restrain the controller, so that changing them never records a new revision
+func recordable(spec *acmeiov1beta1.ApplicationSpec) *acmeiov1beta1.ApplicationSpec {
+	out := spec.DeepCopy()
+	out.RevisionHistoryLimit = nil
+	out.RollbackTo = nil
+	out.Suspend = nil
+	out.DriftPolicy = nil
+	out.DriftIgnore = nil
+
+	return out
+}
+
+// Hash computes a stable, name safe, hash of the recordable fields of an Application spec
+func Hash(spec *acmeiov1beta1.ApplicationSpec) (string, error) {
+	raw, err := json.Marshal(recordable(spec))
+	if err != nil {
+		return "", err
+	}
+
+	hasher := fnv.New32a()
+	hasher.Write(raw)
+
+	return rand.SafeEncodeString(fmt.Sprint(hasher.Sum32())), nil
+}
+
+// New renders a ControllerRevision holding the current spec of the Application at the given revision number,
+// the caller is responsible for setting the controller reference before the object is created
+func New(cr *acmeiov1beta1.Application, revision int64) (*appsv1.ControllerRevision, error) {
+	hash, err := Hash(&cr.Spec)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := json.Marshal(recordable(&cr.Spec))
+	if err != nil {
+		return nil, err
+	}
+
+	generated := &appsv1.ControllerRevision{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "ControllerRevision",
+			APIVersion: "apps/v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-%s", cr.GetName(), hash),
+			Namespace: cr.GetNamespace(),
+			Labels: map[string]string{
+				ApplicationLabel: cr.GetName(),
+				HashLabel:        hash,
+			},
+		},
+		Data:     runtime.RawExtension{Raw: raw},
+		Revision: revision,
+	}
+
+	return generated, nil
+}
+
+// Spec decodes the Application spec that was recorded in the ControllerRevision
+func Spec(rev *appsv1.ControllerRevision) (*acmeiov1beta1.ApplicationSpec, error) {
+	spec := &acmeiov1beta1.ApplicationSpec{}
+	if err := json.Unmarshal(rev.Data.Raw, spec); err != nil {
+		return nil, fmt.Errorf("cannot decode application spec from revision %s: %w", rev.Name, err)
+	}
+
+	return spec, nil
+}
+
+// Sort orders the revision history from the oldest to the newest revision in place
+func Sort(history []appsv1.ControllerRevision) {
+	sort.SliceStable(history, func(i, j int) bool {
+		return history[i].Revision < history[j].Revision
+	})
+}
+
+// Find returns the revision with the given revision number, where 0 selects the revision prior
+// to the newest one, or nil when there is no such revision in the history.  The history is sorted in place.
+func Find(history []appsv1.ControllerRevision, revision int64) *appsv1.ControllerRevision {
+	Sort(history)
+
+	if revision == 0 {
+		if len(history) < 2 {
+			return nil
+		}
+		return &history[len(history)-2]
+	}
+
+	for i := range history {
+		if history[i].Revision == revision {
+			return &history[i]
+		}
+	}
+
+	return nil
+}
+
+// Prunable returns the oldest revisions in the history that exceed the limit of previous revisions to keep,
+// the newest revision is always considered current and is never returned.  The history is sorted in place.
+func Prunable(history []appsv1.ControllerRevision, limit int32) []appsv1.ControllerRevision {
+	Sort(history)
+
+	excess := len(history) - 1 - int(limit)
+	if excess <= 0 {
+		return nil
+	}
+
+	return history[:excess]
+}
@@ -0,0 +1,207 @@
+package revisions
+
+import (
+	"reflect"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	acmeiov1beta1 "github.com/nathanbrophy/portfolio-demo/k8s/api/v1beta1"
+	acmeioutils "github.com/nathanbrophy/portfolio-demo/k8s/utils"
+)
+
+func generateCR(image string) *acmeiov1beta1.Application {
+	return &acmeiov1beta1.Application{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "example",
+			Namespace: "example-ns",
+		},
+		Spec: acmeiov1beta1.ApplicationSpec{
+			Application: &acmeiov1beta1.ApplicationApplication{
+				Image: acmeioutils.StringPointerGenerator(image),
+			},
+		},
+	}
+}
+
+func generateHistory(revisions ...int64) []appsv1.ControllerRevision {
+	history := make([]appsv1.ControllerRevision, len(revisions))
+	for i, r := range revisions {
+		history[i] = appsv1.ControllerRevision{Revision: r}
+	}
+
+	return history
+}
+
+func TestHash(t *testing.T) {
+	base := generateCR("example.com/test-image:v1.0")
+
+	bookkeeping := base.DeepCopy()
+	bookkeeping.Spec.RevisionHistoryLimit = acmeioutils.Int32PointerGenerator(2)
+	bookkeeping.Spec.RollbackTo = &acmeiov1beta1.ApplicationRollback{Revision: 3}
+	bookkeeping.Spec.Suspend = acmeioutils.BoolPointerGenerator(true)
+	bookkeeping.Spec.DriftPolicy = &acmeiov1beta1.ApplicationDriftPolicy{Default: acmeiov1beta1.DriftPolicyReport}
+	bookkeeping.Spec.DriftIgnore = []acmeiov1beta1.ApplicationDriftIgnoreRule{{Kind: "Deployment", Paths: []string{"/spec/replicas"}}}
+
+	changed := generateCR("example.com/test-image:v2.0")
+
+	tests := []struct {
+		name string
+		lhs  *acmeiov1beta1.Application
+		rhs  *acmeiov1beta1.Application
+		want bool
+	}{
+		{
+			name: "same spec",
+			lhs:  base,
+			rhs:  base.DeepCopy(),
+			want: true,
+		},
+		{
+			name: "bookkeeping fields are ignored",
+			lhs:  base,
+			rhs:  bookkeeping,
+			want: true,
+		},
+		{
+			name: "changed spec",
+			lhs:  base,
+			rhs:  changed,
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lhs, err := Hash(&tt.lhs.Spec)
+			if err != nil {
+				t.Fatalf("Hash() error = %v", err)
+			}
+			rhs, err := Hash(&tt.rhs.Spec)
+			if err != nil {
+				t.Fatalf("Hash() error = %v", err)
+			}
+			if got := lhs == rhs; got != tt.want {
+				t.Errorf("Hash() equal = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewAndSpec(t *testing.T) {
+	cr := generateCR("example.com/test-image:v1.0")
+	cr.Spec.RollbackTo = &acmeiov1beta1.ApplicationRollback{Revision: 1}
+
+	rev, err := New(cr, 4)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	hash, _ := Hash(&cr.Spec)
+	if rev.Name != "example-"+hash {
+		t.Errorf("New() name = %v, want %v", rev.Name, "example-"+hash)
+	}
+	if rev.Namespace != "example-ns" || rev.Revision != 4 {
+		t.Errorf("New() namespace = %v revision = %v, want example-ns and 4", rev.Namespace, rev.Revision)
+	}
+	if rev.Labels[ApplicationLabel] != "example" || rev.Labels[HashLabel] != hash {
+		t.Errorf("New() labels = %v", rev.Labels)
+	}
+
+	got, err := Spec(rev)
+	if err != nil {
+		t.Fatalf("Spec() error = %v", err)
+	}
+	want := cr.Spec.DeepCopy()
+	want.RollbackTo = nil
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Spec() = %v, want %v", got, want)
+	}
+}
+
+func TestFind(t *testing.T) {
+	tests := []struct {
+		name     string
+		history  []appsv1.ControllerRevision
+		revision int64
+		want     int64
+		found    bool
+	}{
+		{
+			name:     "exact revision",
+			history:  generateHistory(3, 1, 2),
+			revision: 2,
+			want:     2,
+			found:    true,
+		},
+		{
+			name:     "previous revision",
+			history:  generateHistory(3, 1, 2),
+			revision: 0,
+			want:     2,
+			found:    true,
+		},
+		{
+			name:     "no previous revision",
+			history:  generateHistory(1),
+			revision: 0,
+			found:    false,
+		},
+		{
+			name:     "unknown revision",
+			history:  generateHistory(1, 2),
+			revision: 7,
+			found:    false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Find(tt.history, tt.revision)
+			if (got != nil) != tt.found {
+				t.Fatalf("Find() = %v, want found %v", got, tt.found)
+			}
+			if got != nil && got.Revision != tt.want {
+				t.Errorf("Find() = %v, want %v", got.Revision, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrunable(t *testing.T) {
+	tests := []struct {
+		name    string
+		history []appsv1.ControllerRevision
+		limit   int32
+		want    []int64
+	}{
+		{
+			name:    "within limit",
+			history: generateHistory(1, 2, 3),
+			limit:   2,
+			want:    []int64{},
+		},
+		{
+			name:    "exceeds limit",
+			history: generateHistory(4, 2, 1, 3),
+			limit:   1,
+			want:    []int64{1, 2},
+		},
+		{
+			name:    "keeps the current revision",
+			history: generateHistory(2, 1),
+			limit:   0,
+			want:    []int64{1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []int64{}
+			for _, rev := range Prunable(tt.history, tt.limit) {
+				got = append(got, rev.Revision)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Prunable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+)
+
+// The exporters spans can be sent to
+const (
+	// ExporterNone disables tracing, spans are still created but never recorded
+	ExporterNone string = "none"
+
+	// ExporterOTLP sends spans to an OpenTelemetry collector over OTLP/gRPC
+	ExporterOTLP string = "otlp"
+
+	// ExporterStdout writes spans to standard output, for local testing
+	ExporterStdout string = "stdout"
+)
+
+// Options defines how the spans of the manager are sampled and exported
+type Options struct {
+	// Exporter is one of none, otlp or stdout
+	Exporter string
+
+	// Endpoint is the host and port of the OTLP collector, the OTEL_EXPORTER_OTLP_ENDPOINT environment variable
+	// or localhost:4317 is used when empty
+	Endpoint string
+
+	// Insecure sends spans to the OTLP collector without TLS
+	Insecure bool
+
+	// SampleRatio is the fraction of reconciliations that are traced, the sampling decision of a parent span is
+	// always kept
+	SampleRatio float64
+
+	// ServiceName is the name the spans are reported under
+	ServiceName string
+}
+
+// Setup installs the global tracer provider and propagator for the given options, and returns the function that
+// flushes the remaining spans when the manager stops
+func Setup(ctx context.Context, opts Options) (func(context.Context) error, error) {
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch opts.Exporter {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+		clientOpts := []otlptracegrpc.Option{}
+		if opts.Endpoint != "" {
+			clientOpts = append(clientOpts, otlptracegrpc.WithEndpoint(opts.Endpoint))
+		}
+		if opts.Insecure {
+			clientOpts = append(clientOpts, otlptracegrpc.WithInsecure())
+		}
+		exporter, err = otlptracegrpc.New(ctx, clientOpts...)
+	case ExporterStdout:
+		exporter, err = stdoutExporter(os.Stdout)
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q, expected one of %s, %s or %s", opts.Exporter, ExporterNone, ExporterOTLP, ExporterStdout)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to create the %s tracing exporter: %w", opts.Exporter, err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(opts.ServiceName)))
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	return provider.Shutdown, nil
+}
+
+func stdoutExporter(w io.Writer) (sdktrace.SpanExporter, error) {
+	return stdouttrace.New(stdouttrace.WithWriter(w), stdouttrace.WithPrettyPrint())
+}
@@ -0,0 +1,69 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func TestSetup(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    Options
+		wantErr bool
+	}{
+		{
+			name: "disabled",
+			opts: Options{},
+		},
+		{
+			name: "stdout",
+			opts: Options{Exporter: ExporterStdout, SampleRatio: 1, ServiceName: "acme-controller-manager"},
+		},
+		{
+			name: "otlp",
+			opts: Options{Exporter: ExporterOTLP, Endpoint: "localhost:4317", Insecure: true, SampleRatio: 0.5, ServiceName: "acme-controller-manager"},
+		},
+		{
+			name:    "unknown exporter",
+			opts:    Options{Exporter: "jaeger"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shutdown, err := Setup(context.Background(), tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Setup() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if err := shutdown(context.Background()); err != nil {
+				t.Errorf("Setup() shutdown error = %v", err)
+			}
+		})
+	}
+}
+
+func TestStdoutExporter(t *testing.T) {
+	out := &bytes.Buffer{}
+	exporter, err := stdoutExporter(out)
+	if err != nil {
+		t.Fatalf("stdoutExporter() error = %v", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	_, span := provider.Tracer("test").Start(context.Background(), "Reconcile")
+	span.End()
+	if err := provider.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	if !strings.Contains(out.String(), `"Name": "Reconcile"`) {
+		t.Errorf("stdoutExporter() wrote %q, want the Reconcile span", out.String())
+	}
+}
@@ -14,3 +14,8 @@ func Int64PointerGenerator(x int64) *int64 {
 func StringPointerGenerator(x string) *string {
 	return &x
 }
+
+// BoolPointerGenerator is a wrapper and will return a memory address pointer to the passed in date
+func BoolPointerGenerator(x bool) *bool {
+	return &x
+}
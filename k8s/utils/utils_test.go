@@ -86,3 +86,36 @@ func TestStringPointerGenerator(t *testing.T) {
 		})
 	}
 }
+
+func TestBoolPointerGenerator(t *testing.T) {
+	type args struct {
+		x bool
+	}
+	tests := []struct {
+		name string
+		args args
+		want bool
+	}{
+		{
+			name: "true",
+			args: args{
+				x: true,
+			},
+			want: true,
+		},
+		{
+			name: "false",
+			args: args{
+				x: false,
+			},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := BoolPointerGenerator(tt.args.x); *got != tt.want {
+				t.Errorf("BoolPointerGenerator() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}